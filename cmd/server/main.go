@@ -17,10 +17,56 @@ func main() {
 	// Parse command line flags
 	versionFlag := flag.Bool("version", false, "Print version information")
 	hostFlag := flag.String("host", "", "Ollama host URL (e.g., https://ollama.empyr.cloud)")
-	contextSizeFlag := flag.Int("context-size", core.DefaultContextSize, "Context size for models")
+	contextSizeFlag := flag.Int("context-size", core.DefaultContextSize, "Maximum default context size, capping the context length reported by each model")
 	codeModelFlag := flag.String("code-model", core.DefaultCodeModel, "Model to use for code generation")
 	chatModelFlag := flag.String("chat-model", core.DefaultChatModel, "Model to use for chat")
-	keepAliveFlag := flag.String("keep-alive", core.DefaultKeepAlive, "Keep-alive duration for models")
+	defaultEmbedModel := os.Getenv("OLLAMA_EMBED_MODEL")
+	if defaultEmbedModel == "" {
+		defaultEmbedModel = core.DefaultEmbedModel
+	}
+	embedModelFlag := flag.String("embed-model", defaultEmbedModel, "Model to use for embeddings")
+	defaultKeepAlive := os.Getenv("OLLAMA_KEEP_ALIVE")
+	if defaultKeepAlive == "" {
+		defaultKeepAlive = core.DefaultKeepAlive
+	}
+	keepAliveFlag := flag.String("keep-alive", defaultKeepAlive, "How long models stay loaded after a request: a duration, a number of seconds, 0 to unload right away or -1 to keep them loaded")
+	timeoutFlag := flag.String("timeout", os.Getenv("OLLAMA_TIMEOUT"), "Total timeout per tool call, either a duration or tool=duration pairs (e.g., chat=5m,code=10m)")
+	firstTokenTimeoutFlag := flag.String("first-token-timeout", os.Getenv("OLLAMA_FIRST_TOKEN_TIMEOUT"), "Maximum wait for the first generated token, including model load, as a duration or tool=duration pairs")
+	idleTimeoutFlag := flag.String("idle-timeout", os.Getenv("OLLAMA_IDLE_TIMEOUT"), "Maximum gap between two generated tokens, as a duration or tool=duration pairs")
+	formatRetriesFlag := flag.Int("format-retries", core.GetEnvIntOrDefault("OLLAMA_FORMAT_RETRIES", core.DefaultFormatRetries), "Number of retries when a structured reply does not match the requested format")
+	maxToolIterationsFlag := flag.Int("max-tool-iterations", core.GetEnvIntOrDefault("OLLAMA_MAX_TOOL_ITERATIONS", core.DefaultMaxToolIterations), "Maximum rounds of built-in tool calls per chat request")
+	allowedRootsFlag := flag.String("allowed-roots", os.Getenv("OLLAMA_ALLOWED_ROOTS"), "Directories the server may read local files from, separated by the OS path list separator")
+	maxAttachmentBytesFlag := flag.Int("max-attachment-bytes", core.GetEnvIntOrDefault("OLLAMA_MAX_ATTACHMENT_BYTES", core.DefaultMaxAttachmentBytes), "Maximum size of the files attached to a single chat or code request")
+	batchConcurrencyFlag := flag.Int("batch-concurrency", core.GetEnvIntOrDefault("OLLAMA_BATCH_CONCURRENCY", core.DefaultBatchConcurrency), "Maximum number of chat-batch prompts sent to Ollama at the same time")
+	promptsDirFlag := flag.String("prompts-dir", os.Getenv("OLLAMA_PROMPTS_DIR"), "Directory of JSON prompt files exposed as MCP prompts in addition to the built-in prompts")
+	maxSessionsFlag := flag.Int("max-sessions", core.GetEnvIntOrDefault("OLLAMA_MAX_SESSIONS", core.DefaultMaxSessions), "Maximum number of conversation sessions kept in memory, the least recently used being evicted")
+	sessionTTLFlag := flag.Duration("session-ttl", core.GetEnvDurationOrDefault("OLLAMA_SESSION_TTL", core.DefaultSessionTTL), "How long an idle conversation session is kept")
+	defaultIndexDir := os.Getenv("OLLAMA_INDEX_DIR")
+	if defaultIndexDir == "" {
+		defaultIndexDir = core.DefaultIndexDir()
+	}
+	indexDirFlag := flag.String("index-dir", defaultIndexDir, "Directory where document indexes are stored")
+	cacheFlag := flag.Bool("cache", core.GetEnvBoolOrDefault("OLLAMA_CACHE", false), "Cache the responses of deterministic chat requests (temperature 0 or a seed)")
+	defaultCacheDir := os.Getenv("OLLAMA_CACHE_DIR")
+	if defaultCacheDir == "" {
+		defaultCacheDir = core.DefaultCacheDir()
+	}
+	cacheDirFlag := flag.String("cache-dir", defaultCacheDir, "Directory where cached responses are stored, empty to keep them in memory only")
+	cacheTTLFlag := flag.Duration("cache-ttl", core.GetEnvDurationOrDefault("OLLAMA_CACHE_TTL", core.DefaultCacheTTL), "How long a cached response is reused")
+	cacheMaxEntriesFlag := flag.Int("cache-max-entries", core.GetEnvIntOrDefault("OLLAMA_CACHE_MAX_ENTRIES", core.DefaultCacheMaxEntries), "Maximum number of cached responses")
+	retryAttemptsFlag := flag.Int("retry-attempts", core.GetEnvIntOrDefault("OLLAMA_RETRY_ATTEMPTS", core.DefaultRetryAttempts), "Attempts made for requests failing with transient Ollama errors, 1 to disable retries")
+	retryBackoffFlag := flag.Duration("retry-backoff", core.GetEnvDurationOrDefault("OLLAMA_RETRY_BACKOFF", core.DefaultRetryBackoff), "Wait before the first retry, doubled after each retry")
+	retryMaxBackoffFlag := flag.Duration("retry-max-backoff", core.GetEnvDurationOrDefault("OLLAMA_RETRY_MAX_BACKOFF", core.DefaultRetryMaxBackoff), "Maximum wait between two attempts")
+	fallbackModelsFlag := flag.String("fallback-models", os.Getenv("OLLAMA_FALLBACK_MODELS"), "Models tried in order when the default model of a tool is missing, out of memory or too slow to start, as tool=model|model pairs (e.g., code=qwen2.5-coder:7b,chat=llama3.2)")
+	autoPullFlag := flag.Bool("auto-pull", core.GetEnvBoolOrDefault("OLLAMA_AUTO_PULL", false), "Pull missing models before answering chat and code calls")
+	autoPullAllowlistFlag := flag.String("auto-pull-allowlist", os.Getenv("OLLAMA_AUTO_PULL_ALLOWLIST"), "Comma-separated model names or patterns that may be pulled automatically (e.g., qwen2.5-coder:*), the configured models when empty")
+	modelKeepAliveFlag := flag.String("model-keep-alive", os.Getenv("OLLAMA_MODEL_KEEP_ALIVE"), "Keep-alive of specific models, as model=duration pairs (e.g., qwen3-coder:30b=2h,llava=0)")
+	modelContextSizesFlag := flag.String("model-context-sizes", os.Getenv("OLLAMA_MODEL_CONTEXT_SIZES"), "Default context sizes of specific models, as model=size pairs (e.g., qwen3-coder:30b=65536,llama3.2=8192)")
+	defaultContextStrategy := os.Getenv("OLLAMA_CONTEXT_STRATEGY")
+	if defaultContextStrategy == "" {
+		defaultContextStrategy = core.DefaultContextStrategy
+	}
+	contextStrategyFlag := flag.String("context-strategy", defaultContextStrategy, "What to do when a prompt does not fit in the context size: raise num_ctx up to the model's maximum, reject the request, trim older session messages, or none")
 	flag.Parse()
 
 	// Handle version flag
@@ -34,6 +80,46 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	config.EmbedModel = *embedModelFlag
+	if _, err := core.ParseKeepAlive(config.KeepAlive); err != nil {
+		log.Fatalf("Invalid keep-alive: %v", err)
+	}
+	if config.ModelKeepAlive, err = core.ParseModelKeepAlive(*modelKeepAliveFlag); err != nil {
+		log.Fatalf("Invalid model keep-alive: %v", err)
+	}
+	config.FormatRetries = *formatRetriesFlag
+	config.MaxToolIterations = *maxToolIterationsFlag
+	config.AllowedRoots = core.ParsePathList(*allowedRootsFlag)
+	config.MaxAttachmentBytes = *maxAttachmentBytesFlag
+	config.MaxSessions = *maxSessionsFlag
+	config.SessionTTL = *sessionTTLFlag
+	config.IndexDir = *indexDirFlag
+	config.BatchConcurrency = *batchConcurrencyFlag
+	config.PromptsDir = *promptsDirFlag
+	config.CacheEnabled = *cacheFlag
+	config.CacheDir = *cacheDirFlag
+	config.CacheTTL = *cacheTTLFlag
+	config.CacheMaxEntries = *cacheMaxEntriesFlag
+	if config.ModelContextSizes, err = core.ParseModelContextSizes(*modelContextSizesFlag); err != nil {
+		log.Fatalf("Invalid model context sizes: %v", err)
+	}
+	config.ContextStrategy = *contextStrategyFlag
+	if !core.ValidContextStrategy(config.ContextStrategy) {
+		log.Fatalf("Invalid context strategy: %s", config.ContextStrategy)
+	}
+	config.AutoPull = *autoPullFlag
+	config.AutoPullAllowlist = core.ParseModelList(*autoPullAllowlistFlag)
+	config.Retry = core.RetryPolicy{
+		MaxAttempts:    *retryAttemptsFlag,
+		InitialBackoff: *retryBackoffFlag,
+		MaxBackoff:     *retryMaxBackoffFlag,
+	}
+	if err := config.SetTimeouts(*timeoutFlag, *firstTokenTimeoutFlag, *idleTimeoutFlag); err != nil {
+		log.Fatalf("Invalid timeout: %v", err)
+	}
+	if config.FallbackModels, err = core.ParseFallbackModels(*fallbackModelsFlag); err != nil {
+		log.Fatalf("Invalid fallback models: %v", err)
+	}
 
 	// Create our server instance with dependency injection
 	ollamaServer := core.NewServer(config)
@@ -45,6 +131,7 @@ func main() {
 	// Get model names for descriptions
 	codeModel := config.CodeModel
 	chatModel := config.ChatModel
+	embedModel := config.EmbedModel
 
 	// Add the code tool with the configured model
 	mcp.AddTool(server, &mcp.Tool{Name: "code", Description: fmt.Sprintf("code with %s", codeModel)}, handlerFactory.CodeHandler())
@@ -52,6 +139,23 @@ func main() {
 	// Add the chat tool with the configured model
 	mcp.AddTool(server, &mcp.Tool{Name: "chat", Description: fmt.Sprintf("chat with %s", chatModel)}, handlerFactory.ChatHandler())
 
+	// Add the batch chat tool
+	mcp.AddTool(server, &mcp.Tool{Name: "chat-batch", Description: fmt.Sprintf("run many chat prompts with %s concurrently and return the results in order", chatModel)}, handlerFactory.ChatBatchHandler())
+
+	// Add the model comparison tool
+	mcp.AddTool(server, &mcp.Tool{Name: "compare-models", Description: "send the same message to several models and compare their answers, latency and token metrics"}, handlerFactory.CompareModelsHandler())
+
+	// Add the fill-in-the-middle completion tool with the code model
+	mcp.AddTool(server, &mcp.Tool{Name: "complete", Description: fmt.Sprintf("complete code between a prefix and a suffix with %s", codeModel)}, handlerFactory.CompleteHandler())
+
+	// Add the embeddings tool with the configured model
+	mcp.AddTool(server, &mcp.Tool{Name: "embed", Description: fmt.Sprintf("compute text embeddings and similarity scores with %s", embedModel)}, handlerFactory.EmbedHandler())
+
+	// Add the document retrieval tools
+	mcp.AddTool(server, &mcp.Tool{Name: "index-documents", Description: "index local text and markdown files into an on-disk vector index"}, handlerFactory.IndexDocumentsHandler())
+	mcp.AddTool(server, &mcp.Tool{Name: "search-documents", Description: "search indexed documents for the passages closest to a query"}, handlerFactory.SearchDocumentsHandler())
+	mcp.AddTool(server, &mcp.Tool{Name: "ask-documents", Description: fmt.Sprintf("answer a question from indexed documents with %s, citing files and line ranges", chatModel)}, handlerFactory.AskDocumentsHandler())
+
 	// Add the list models tool
 	mcp.AddTool(server, &mcp.Tool{Name: "list-models", Description: "list available Ollama models"}, handlerFactory.ListModelsHandler())
 
@@ -61,6 +165,30 @@ func main() {
 	// Add the pull model tool
 	mcp.AddTool(server, &mcp.Tool{Name: "pull-model", Description: "pull a model from the Ollama library"}, handlerFactory.PullModelHandler())
 
+	// Add the session management tools
+	mcp.AddTool(server, &mcp.Tool{Name: "create-session", Description: "create a conversation session that keeps message history across chat and code calls"}, handlerFactory.CreateSessionHandler())
+	mcp.AddTool(server, &mcp.Tool{Name: "list-sessions", Description: "list active conversation sessions"}, handlerFactory.ListSessionsHandler())
+	mcp.AddTool(server, &mcp.Tool{Name: "session-info", Description: "get the message history of a conversation session"}, handlerFactory.SessionInfoHandler())
+	mcp.AddTool(server, &mcp.Tool{Name: "reset-session", Description: "clear the message history of a conversation session"}, handlerFactory.ResetSessionHandler())
+	mcp.AddTool(server, &mcp.Tool{Name: "delete-session", Description: "delete a conversation session and its message history"}, handlerFactory.DeleteSessionHandler())
+
+	// Add the response cache tool
+	mcp.AddTool(server, &mcp.Tool{Name: "cache-clear", Description: "remove every cached chat response"}, handlerFactory.ClearCacheHandler())
+
+	// Expose the models as resources
+	server.AddResource(&mcp.Resource{URI: core.ModelsResourceURI, Name: "models", Description: "available Ollama models", MIMEType: "application/json"}, handlerFactory.ModelsResourceHandler())
+	server.AddResourceTemplate(&mcp.ResourceTemplate{URITemplate: core.ModelResourceTemplate, Name: "model", Description: "details of an Ollama model, with the tag escaped as %3A", MIMEType: "application/json"}, handlerFactory.ModelResourceHandler())
+	server.AddResourceTemplate(&mcp.ResourceTemplate{URITemplate: core.ModelfileResourceTemplate, Name: "modelfile", Description: "raw Modelfile of an Ollama model, with the tag escaped as %3A", MIMEType: "text/plain"}, handlerFactory.ModelResourceHandler())
+
+	// Add the built-in and user prompts
+	prompts := core.NewPromptRegistry()
+	if config.PromptsDir != "" {
+		if err := prompts.LoadDir(config.PromptsDir); err != nil {
+			log.Fatalf("Failed to load prompts: %v", err)
+		}
+	}
+	prompts.Register(server)
+
 	// Run the server over stdin/stdout, until the client disconnects
 	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ollama/ollama/api"
+)
+
+// toolset holds the tools offered to the model for a single chat request
+type toolset struct {
+	// builtin lists the built-in tools the server executes itself
+	builtin map[string]builtinTool
+}
+
+// buildTools converts the caller's tool definitions and built-in tool names
+// into the tools sent to Ollama
+func buildTools(definitions []ToolDefinition, builtinNames []string) (api.Tools, *toolset, error) {
+	var tools api.Tools
+	set := &toolset{builtin: make(map[string]builtinTool)}
+
+	for _, definition := range definitions {
+		tool, err := definition.apiTool()
+		if err != nil {
+			return nil, nil, err
+		}
+		tools = append(tools, tool)
+	}
+
+	for _, name := range builtinNames {
+		builtin, ok := builtinTools[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown built-in tool: %s", name)
+		}
+		for _, definition := range definitions {
+			if definition.Name == name {
+				return nil, nil, fmt.Errorf("tool %s is defined both by the caller and as a built-in tool", name)
+			}
+		}
+		set.builtin[name] = builtin
+		tools = append(tools, builtin.apiTool(name))
+	}
+
+	return tools, set, nil
+}
+
+// apiTool converts a tool definition to its Ollama representation
+func (d ToolDefinition) apiTool() (api.Tool, error) {
+	if d.Name == "" {
+		return api.Tool{}, fmt.Errorf("tool name cannot be empty")
+	}
+
+	parameters := api.ToolFunctionParameters{Type: "object"}
+	if d.Parameters != nil {
+		raw, err := json.Marshal(d.Parameters)
+		if err != nil {
+			return api.Tool{}, fmt.Errorf("invalid parameters for tool %s: %w", d.Name, err)
+		}
+		if err := json.Unmarshal(raw, &parameters); err != nil {
+			return api.Tool{}, fmt.Errorf("invalid parameters for tool %s: %w", d.Name, err)
+		}
+	}
+
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        d.Name,
+			Description: d.Description,
+			Parameters:  parameters,
+		},
+	}, nil
+}
+
+// chatWithTools sends a chat request and executes the built-in tool calls of the model,
+// feeding their results back until it answers, calls a caller-defined tool, or the
+// iteration limit is reached.
+func (h *HandlerFactory) chatWithTools(ctx context.Context, call *chatCall, chatRequest *api.ChatRequest) (api.ChatResponse, []ToolExecution, error) {
+	var executions []ToolExecution
+	var metrics api.Metrics
+	messages := chatRequest.Messages
+
+	for iteration := 0; ; iteration++ {
+		request := *chatRequest
+		request.Messages = messages
+
+		response, err := h.chat(ctx, call, &request)
+		addMetrics(&metrics, response.Metrics)
+		response.Metrics = metrics
+		if err != nil {
+			return response, executions, err
+		}
+
+		tools := call.tools
+		calls := response.Message.ToolCalls
+		if len(calls) == 0 || tools == nil || len(tools.builtin) == 0 {
+			return response, executions, nil
+		}
+
+		// Hand the calls back to the caller as soon as one is not a built-in tool
+		for _, toolCall := range calls {
+			if _, ok := tools.builtin[toolCall.Function.Name]; !ok {
+				return response, executions, nil
+			}
+		}
+
+		if iteration >= call.config.MaxToolIterations {
+			return response, executions, fmt.Errorf("model did not answer after %d tool iterations", iteration)
+		}
+
+		messages = append(messages, response.Message)
+		for _, toolCall := range calls {
+			execution := ToolExecution{Name: toolCall.Function.Name, Arguments: toolArguments(toolCall.Function.Arguments)}
+
+			result, err := tools.builtin[toolCall.Function.Name].run(ctx, call.config, toolCall.Function.Arguments)
+			if err != nil {
+				execution.Error = err.Error()
+				result = "error: " + err.Error()
+			} else {
+				execution.Result = result
+			}
+			executions = append(executions, execution)
+
+			messages = append(messages, api.Message{
+				Role:     "tool",
+				Content:  result,
+				ToolName: toolCall.Function.Name,
+			})
+		}
+	}
+}
+
+// toolResultMessages converts the results of the caller's tool calls to tool messages,
+// checking that they answer the calls of the last reply in the session
+func toolResultMessages(session *Session, results []ToolResult) ([]api.Message, error) {
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	pending := make(map[string]bool)
+	if session != nil && len(session.Messages) > 0 {
+		if last := session.Messages[len(session.Messages)-1]; last.Role == "assistant" {
+			for _, call := range last.ToolCalls {
+				pending[call.Function.Name] = true
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return nil, fmt.Errorf("tool_results need a session whose last reply called tools")
+	}
+
+	messages := make([]api.Message, len(results))
+	for i, result := range results {
+		if !pending[result.Name] {
+			return nil, fmt.Errorf("tool result %d: the last reply did not call %s", i+1, result.Name)
+		}
+		messages[i] = api.Message{Role: "tool", Content: result.Content, ToolName: result.Name}
+	}
+	return messages, nil
+}
+
+// toolCalls converts the model's tool calls to the tool output representation
+func toolCalls(calls []api.ToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	converted := make([]ToolCall, len(calls))
+	for i, call := range calls {
+		converted[i] = ToolCall{
+			Name:      call.Function.Name,
+			Arguments: toolArguments(call.Function.Arguments),
+		}
+	}
+	return converted
+}
+
+// toolArguments returns the arguments of a tool call as a non-nil map
+func toolArguments(args api.ToolCallFunctionArguments) map[string]any {
+	if args == nil {
+		return map[string]any{}
+	}
+	return args
+}
@@ -0,0 +1,186 @@
+package core_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Tool calling", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		config = &core.Config{
+			Client:            ollama.Client(),
+			ContextSize:       32000,
+			ChatModel:         "test-chat-model",
+			KeepAlive:         "1m",
+			MaxToolIterations: 3,
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	toolCallResponse := func(name string, args map[string]any) []api.ChatResponse {
+		return []api.ChatResponse{{
+			Message: api.Message{
+				Role:      "assistant",
+				ToolCalls: []api.ToolCall{{Function: api.ToolCallFunction{Name: name, Arguments: args}}},
+			},
+			Done: true,
+		}}
+	}
+
+	It("should return caller-defined tool calls", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return toolCallResponse("get_weather", map[string]any{"city": "Paris"})
+		}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			Message: "weather?",
+			Tools: []core.ToolDefinition{{
+				Name: "get_weather",
+				Parameters: map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"city": map[string]any{"type": "string"}},
+				},
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.ToolCalls).To(HaveLen(1))
+		Expect(output.ToolCalls[0].Name).To(Equal("get_weather"))
+		Expect(output.ToolCalls[0].Arguments).To(HaveKeyWithValue("city", "Paris"))
+		Expect(ollama.ChatRequests()[0].Tools[0].Function.Parameters.Properties).To(HaveKey("city"))
+	})
+
+	It("should send the caller's tool results back in the session", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			last := req.Messages[len(req.Messages)-1]
+			if last.Role == "tool" {
+				return []api.ChatResponse{{Message: api.Message{Role: "assistant", Content: "it is " + last.Content}, Done: true}}
+			}
+			return toolCallResponse("get_weather", map[string]any{"city": "Paris"})
+		}
+		session, err := factory.GetServer().GetSessions().Create("")
+		Expect(err).NotTo(HaveOccurred())
+		tools := []core.ToolDefinition{{Name: "get_weather"}}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{SessionID: session.ID, Message: "weather?", Tools: tools})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.ToolCalls).To(HaveLen(1))
+
+		_, output, err = factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			SessionID:   session.ID,
+			Tools:       tools,
+			ToolResults: []core.ToolResult{{Name: "get_weather", Content: "sunny"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Response).To(Equal("it is sunny"))
+
+		messages := ollama.ChatRequests()[1].Messages
+		Expect(messages).To(HaveLen(3))
+		Expect(messages[1].ToolCalls).To(HaveLen(1))
+		Expect(messages[2]).To(Equal(api.Message{Role: "tool", Content: "sunny", ToolName: "get_weather"}))
+
+		session, err = factory.GetServer().GetSessions().Get(session.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(session.Messages).To(HaveLen(4))
+		Expect(session.Messages[3].Content).To(Equal("it is sunny"))
+	})
+
+	It("should reject tool results that answer no pending call", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{ToolResults: []core.ToolResult{{Name: "get_weather", Content: "sunny"}}})
+		Expect(err).To(MatchError(ContainSubstring("need a session whose last reply called tools")))
+
+		session, err := factory.GetServer().GetSessions().Create("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(factory.GetServer().GetSessions().Append(session.ID,
+			api.Message{Role: "user", Content: "weather?"},
+			api.Message{Role: "assistant", ToolCalls: []api.ToolCall{{Function: api.ToolCallFunction{Name: "get_weather"}}}},
+		)).To(Succeed())
+
+		_, _, err = factory.ChatHandler()(context.Background(), nil, core.ChatInput{SessionID: session.ID, ToolResults: []core.ToolResult{{Name: "get_time", Content: "noon"}}})
+		Expect(err).To(MatchError(ContainSubstring("the last reply did not call get_time")))
+		Expect(ollama.ChatRequests()).To(BeEmpty())
+	})
+
+	It("should execute built-in tools until the model answers", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			last := req.Messages[len(req.Messages)-1]
+			if last.Role == "tool" {
+				return []api.ChatResponse{{Message: api.Message{Role: "assistant", Content: "it is " + last.Content}, Done: true}}
+			}
+			return toolCallResponse("calculator", map[string]any{"expression": "(2 + 3) * 4"})
+		}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			Message:      "compute",
+			BuiltinTools: []string{"calculator"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Response).To(Equal("it is 20"))
+		Expect(output.ToolCalls).To(BeEmpty())
+		Expect(output.ToolExecutions).To(HaveLen(1))
+		Expect(output.ToolExecutions[0].Result).To(Equal("20"))
+	})
+
+	It("should stop after the iteration limit", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return toolCallResponse("current_time", nil)
+		}
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			Message:      "loop",
+			BuiltinTools: []string{"current_time"},
+		})
+		Expect(err).To(MatchError(ContainSubstring("3 tool iterations")))
+		Expect(ollama.ChatRequests()).To(HaveLen(4))
+	})
+
+	It("should keep file tools inside the allowed roots", func() {
+		root := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(root, "notes.txt"), []byte("secret plan"), 0o600)).To(Succeed())
+		config.AllowedRoots = []string{root}
+
+		var reads []string
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			last := req.Messages[len(req.Messages)-1]
+			if last.Role == "tool" {
+				reads = append(reads, last.Content)
+				if len(reads) == 2 {
+					return []api.ChatResponse{{Message: api.Message{Role: "assistant", Content: "done"}, Done: true}}
+				}
+				return toolCallResponse("read_file", map[string]any{"path": "../../etc/passwd"})
+			}
+			return toolCallResponse("read_file", map[string]any{"path": "notes.txt"})
+		}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			Message:      "read",
+			BuiltinTools: []string{"read_file"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reads[0]).To(Equal("secret plan"))
+		Expect(output.ToolExecutions[1].Error).NotTo(BeEmpty())
+	})
+
+	It("should reject unknown built-in tools", func() {
+		err := factory.ValidateChatInput(core.ChatInput{Message: "hi", BuiltinTools: []string{"shell"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
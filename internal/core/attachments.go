@@ -0,0 +1,209 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DefaultMaxAttachmentBytes limits the size of the files attached to a single request
+const DefaultMaxAttachmentBytes = 256 * 1024
+
+// FileInput is a local file attached to a chat message, optionally limited to a range of lines
+type FileInput struct {
+	Path      string `json:"path" jsonschema:"path of a local text file inside the workspace roots"`
+	StartLine int    `json:"start_line,omitempty" jsonschema:"first line to attach, starting at 1 (optional)"`
+	EndLine   int    `json:"end_line,omitempty" jsonschema:"last line to attach, included (optional)"`
+}
+
+// validate checks the path and line range of the file
+func (f FileInput) validate() error {
+	if f.Path == "" {
+		return fmt.Errorf("path cannot be empty")
+	}
+	if f.StartLine < 0 || f.EndLine < 0 {
+		return fmt.Errorf("line numbers must be positive")
+	}
+	if f.StartLine > 0 && f.EndLine > 0 && f.EndLine < f.StartLine {
+		return fmt.Errorf("end_line %d is before start_line %d", f.EndLine, f.StartLine)
+	}
+	return nil
+}
+
+// label names the attached file in the prompt, with its line range when one was requested
+func (f FileInput) label(lines int) string {
+	if f.StartLine == 0 && f.EndLine == 0 {
+		return f.Path
+	}
+	return fmt.Sprintf("%s:%d-%d", f.Path, max(f.StartLine, 1), lines)
+}
+
+// attachFiles reads the attached files and returns them inlined before the message,
+// as fenced blocks labeled with their path. Files are read from the allowed roots,
+// or from the roots shared by the MCP client when none are configured.
+func attachFiles(ctx context.Context, req *mcp.CallToolRequest, config *Config, inputs []FileInput, message string) (string, error) {
+	if len(inputs) == 0 {
+		return message, nil
+	}
+
+	roots, err := attachmentRoots(ctx, req, config)
+	if err != nil {
+		return "", err
+	}
+
+	limit := config.MaxAttachmentBytes
+	if limit <= 0 {
+		limit = DefaultMaxAttachmentBytes
+	}
+
+	var b strings.Builder
+	size := 0
+	for i, input := range inputs {
+		if err := input.validate(); err != nil {
+			return "", fmt.Errorf("file %d: %w", i+1, err)
+		}
+
+		content, label, err := readAttachment(roots, input, limit-size)
+		if err != nil {
+			return "", fmt.Errorf("file %s: %w", input.Path, err)
+		}
+		size += len(content)
+
+		// The fence is longer than any backtick run of the file so that it cannot be closed early
+		fence := "```"
+		for strings.Contains(content, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&b, "%s\n%s\n%s", label, fence, content)
+		if !strings.HasSuffix(content, "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString(fence + "\n\n")
+	}
+
+	b.WriteString(message)
+	return b.String(), nil
+}
+
+// attachmentRoots returns the directories attached files may be read from
+func attachmentRoots(ctx context.Context, req *mcp.CallToolRequest, config *Config) ([]string, error) {
+	if len(config.AllowedRoots) > 0 {
+		return config.AllowedRoots, nil
+	}
+	if req == nil || req.Session == nil {
+		return nil, fmt.Errorf("file access is disabled: no allowed roots configured")
+	}
+
+	result, err := req.Session.ListRoots(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("file access is disabled: no allowed roots configured and the client roots are unavailable: %w", err)
+	}
+
+	var roots []string
+	for _, root := range result.Roots {
+		u, err := url.Parse(root.URI)
+		if err != nil || u.Scheme != "file" || u.Path == "" {
+			continue
+		}
+		roots = append(roots, u.Path)
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("file access is disabled: no allowed roots configured and the client shares no file roots")
+	}
+	return roots, nil
+}
+
+// readAttachment reads a text file inside the roots, keeping the requested lines, and
+// returns its content and label. The content must fit in the remaining byte budget.
+func readAttachment(roots []string, input FileInput, budget int) (string, string, error) {
+	resolved, err := resolveAllowedPath(roots, input.Path)
+	if err != nil {
+		return "", "", err
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", "", err
+	}
+	if info.IsDir() {
+		return "", "", fmt.Errorf("path is a directory")
+	}
+	// Whole files are rejected before being read, line ranges while they are read
+	if input.StartLine == 0 && input.EndLine == 0 && info.Size() > int64(budget) {
+		return "", "", attachmentBudgetError(budget)
+	}
+
+	start := max(input.StartLine, 1)
+	content, lines, err := readLines(resolved, start, input.EndLine, budget)
+	if err != nil {
+		return "", "", err
+	}
+	if start > lines {
+		return "", "", fmt.Errorf("start_line %d is past the end of the file (%d lines)", start, lines)
+	}
+	if input.EndLine > 0 {
+		lines = min(lines, input.EndLine)
+	}
+	return content, input.label(lines), nil
+}
+
+// readLines reads the lines from start to end of a text file, end 0 meaning the last
+// line, and returns them with the number of lines read. Reading stops after the end
+// line or as soon as the kept lines exceed the budget, so that a line range of a large
+// file is not loaded in memory.
+func readLines(path string, start, end, budget int) (string, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	reader := bufio.NewReader(f)
+	var b strings.Builder
+	lines := 0
+	lineStart := true
+	for end == 0 || !lineStart || lines < end {
+		// Long lines are read in several fragments
+		fragment, err := reader.ReadSlice('\n')
+		if len(fragment) > 0 {
+			if bytes.IndexByte(fragment, 0) >= 0 {
+				return "", 0, fmt.Errorf("file is not a text file")
+			}
+			if lineStart {
+				lines++
+			}
+			if lines >= start {
+				if b.Len()+len(fragment) > budget {
+					return "", 0, attachmentBudgetError(budget)
+				}
+				b.Write(fragment)
+			}
+			lineStart = fragment[len(fragment)-1] == '\n'
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return "", 0, err
+		}
+	}
+
+	content := b.String()
+	if !utf8.ValidString(content) {
+		return "", 0, fmt.Errorf("file is not a text file")
+	}
+	return content, lines, nil
+}
+
+// attachmentBudgetError reports an attachment exceeding the remaining budget of the request
+func attachmentBudgetError(budget int) error {
+	return fmt.Errorf("attachments exceed the remaining budget of %d bytes (about %d tokens); attach a line range instead", budget, budget/charsPerToken)
+}
@@ -0,0 +1,155 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("File attachments", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+		root    string
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		root = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "logo.bin"), []byte{0x89, 'P', 'N', 'G', 0, 0}, 0o644)).To(Succeed())
+
+		config = &core.Config{
+			Client:       ollama.Client(),
+			ContextSize:  32000,
+			CodeModel:    "test-code-model",
+			ChatModel:    "test-chat-model",
+			KeepAlive:    "1m",
+			AllowedRoots: []string{root},
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	sentMessage := func() string {
+		requests := ollama.ChatRequests()
+		messages := requests[len(requests)-1].Messages
+		return messages[len(messages)-1].Content
+	}
+
+	It("should inline the files before the message", func() {
+		_, _, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{
+			Message: "review this file",
+			Files:   []core.FileInput{{Path: "main.go"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentMessage()).To(Equal("main.go\n```\npackage main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n```\n\nreview this file"))
+	})
+
+	It("should attach a range of lines", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			Message: "explain",
+			Files:   []core.FileInput{{Path: "main.go", StartLine: 3, EndLine: 10}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentMessage()).To(HavePrefix("main.go:3-5\n```\nfunc main() {\n\tprintln(\"hi\")\n}\n```\n"))
+	})
+
+	It("should use a longer fence for files containing fences", func() {
+		Expect(os.WriteFile(filepath.Join(root, "README.md"), []byte("```sh\nmake\n```\n"), 0o644)).To(Succeed())
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "explain", Files: []core.FileInput{{Path: "README.md"}}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentMessage()).To(HavePrefix("README.md\n````\n```sh\nmake\n```\n````\n"))
+	})
+
+	It("should reject files outside the roots", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "explain", Files: []core.FileInput{{Path: "/etc/hosts"}}})
+		Expect(err).To(MatchError(ContainSubstring("outside the allowed roots")))
+		Expect(ollama.ChatRequests()).To(BeEmpty())
+	})
+
+	It("should reject binary files", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "explain", Files: []core.FileInput{{Path: "logo.bin"}}})
+		Expect(err).To(MatchError(ContainSubstring("not a text file")))
+	})
+
+	It("should reject invalid line ranges", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "explain", Files: []core.FileInput{{Path: "main.go", StartLine: 4, EndLine: 2}}})
+		Expect(err).To(MatchError(ContainSubstring("before start_line")))
+
+		_, _, err = factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "explain", Files: []core.FileInput{{Path: "main.go", StartLine: 40}}})
+		Expect(err).To(MatchError(ContainSubstring("past the end of the file")))
+	})
+
+	It("should enforce the attachment budget across files", func() {
+		config.MaxAttachmentBytes = 64
+		Expect(os.WriteFile(filepath.Join(root, "big.txt"), []byte(strings.Repeat("x", 100)), 0o644)).To(Succeed())
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "explain", Files: []core.FileInput{{Path: "big.txt"}}})
+		Expect(err).To(MatchError(ContainSubstring("budget of 64 bytes")))
+
+		_, _, err = factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "explain", Files: []core.FileInput{{Path: "main.go"}, {Path: "main.go"}}})
+		Expect(err).To(MatchError(ContainSubstring("remaining budget of 19 bytes")))
+	})
+
+	It("should attach a range of lines of a file larger than the budget", func() {
+		config.MaxAttachmentBytes = 64
+		var b strings.Builder
+		for i := 1; i <= 10000; i++ {
+			fmt.Fprintf(&b, "line %d\n", i)
+		}
+		Expect(os.WriteFile(filepath.Join(root, "big.log"), []byte(b.String()), 0o644)).To(Succeed())
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "explain", Files: []core.FileInput{{Path: "big.log", StartLine: 2, EndLine: 3}}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentMessage()).To(HavePrefix("big.log:2-3\n```\nline 2\nline 3\n```\n"))
+
+		_, _, err = factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "explain", Files: []core.FileInput{{Path: "big.log", StartLine: 9000}}})
+		Expect(err).To(MatchError(ContainSubstring("budget of 64 bytes")))
+	})
+
+	It("should read from the client roots when no roots are configured", func() {
+		config.AllowedRoots = nil
+
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "test"}, nil)
+		mcp.AddTool(server, &mcp.Tool{Name: "chat"}, factory.ChatHandler())
+		serverTransport, clientTransport := mcp.NewInMemoryTransports()
+		_, err := server.Connect(context.Background(), serverTransport, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+		client.AddRoots(&mcp.Root{URI: "file://" + root})
+		session, err := client.Connect(context.Background(), clientTransport, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer func() { _ = session.Close() }()
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "chat",
+			Arguments: map[string]any{"model": "test-chat-model", "message": "explain", "files": []map[string]any{{"path": filepath.Join(root, "main.go")}}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.IsError).To(BeFalse())
+		Expect(sentMessage()).To(ContainSubstring("func main() {"))
+	})
+
+	It("should refuse files without any roots", func() {
+		config.AllowedRoots = nil
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "explain", Files: []core.FileInput{{Path: "main.go"}}})
+		Expect(err).To(MatchError(ContainSubstring("file access is disabled")))
+	})
+})
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// ParseModelList splits a comma-separated list of model names or patterns, dropping empty entries
+func ParseModelList(value string) []string {
+	var models []string
+	for _, model := range strings.Split(value, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// CanAutoPull reports whether a missing model may be pulled automatically. Models must
+// match a pattern of the allowlist, such as "qwen2.5-coder:*", names without a tag standing
+// for the latest tag. Without an allowlist only
+// the configured default and fallback models may be pulled.
+func (c *Config) CanAutoPull(model string) bool {
+	if !c.AutoPull {
+		return false
+	}
+
+	patterns := c.AutoPullAllowlist
+	if len(patterns) == 0 {
+		patterns = []string{c.CodeModel, c.ChatModel, c.EmbedModel}
+		for _, fallbacks := range c.FallbackModels {
+			patterns = append(patterns, fallbacks...)
+		}
+	}
+
+	name := withDefaultTag(model)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(withDefaultTag(pattern), name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isModelNotFound reports whether Ollama failed because the model is not installed
+func isModelNotFound(err error) bool {
+	var statusErr api.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// pullModel pulls a model from the Ollama library, forwarding the download progress
+// to the caller as progress notifications when progress is set. Ollama reports the
+// progress of each layer separately, so the message carries the percentage of all
+// the layers seen so far.
+func (h *HandlerFactory) pullModel(ctx context.Context, progress *progressCounter, name string, insecure bool) error {
+	client := h.server.GetClient()
+	if client == nil {
+		return fmt.Errorf("ollama client not initialized")
+	}
+
+	pullRequest := &api.PullRequest{
+		Model:    name,
+		Insecure: insecure,
+	}
+
+	// Pull operations can take a long time, so no timeout is added here
+	completed := make(map[string]int64)
+	totals := make(map[string]int64)
+	return client.Pull(ctx, pullRequest, func(response api.ProgressResponse) error {
+		if progress == nil {
+			return nil
+		}
+
+		message := fmt.Sprintf("pulling %s: %s", name, response.Status)
+		if response.Digest != "" && response.Total > 0 {
+			completed[response.Digest] = response.Completed
+			totals[response.Digest] = response.Total
+		}
+		var done, total int64
+		for digest, size := range totals {
+			done += completed[digest]
+			total += size
+		}
+		if total > 0 {
+			message += fmt.Sprintf(" (%d%%)", done*100/total)
+		}
+		progress.advance(ctx, 1, message)
+		return nil
+	})
+}
+
+// autoPull pulls a model that Ollama reported missing when the auto-pull policy allows it,
+// reporting the download with the progress of the chat call that needs the model.
+// It returns nil once the model is installed, or the error to report instead of err,
+// which still wraps err so that the model can be replaced by a fallback.
+func (h *HandlerFactory) autoPull(ctx context.Context, call *chatCall, model string, err error) error {
+	if !call.config.AutoPull {
+		return err
+	}
+	if !call.config.CanAutoPull(model) {
+		return fmt.Errorf("%w (auto-pull is not allowed for %s)", err, model)
+	}
+
+	if pullErr := h.pullModel(ctx, call.progress, model, false); pullErr != nil {
+		return fmt.Errorf("%w (auto-pull failed: %v)", err, pullErr)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package core_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Auto-pull", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			if !ollama.installed(req.Model) {
+				return nil
+			}
+			return []api.ChatResponse{{Message: api.Message{Role: "assistant", Content: "answer from " + req.Model}, Done: true}}
+		}
+		config = &core.Config{
+			Client:            ollama.Client(),
+			ContextSize:       32000,
+			CodeModel:         "qwen3-coder:30b",
+			ChatModel:         "gpt-oss:20b",
+			KeepAlive:         "1m",
+			AutoPull:          true,
+			AutoPullAllowlist: []string{"qwen2.5-coder:*", "llama3.2"},
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should match models against the allowlist", func() {
+		Expect(config.CanAutoPull("qwen2.5-coder:7b")).To(BeTrue())
+		Expect(config.CanAutoPull("llama3.2:latest")).To(BeTrue())
+		Expect(config.CanAutoPull("llama3.2:1b")).To(BeFalse())
+		Expect(config.CanAutoPull("llama3.2")).To(BeTrue())
+		Expect(config.CanAutoPull("qwen3-coder:30b")).To(BeFalse())
+
+		config.AutoPullAllowlist = nil
+		Expect(config.CanAutoPull("qwen3-coder:30b")).To(BeTrue())
+		Expect(config.CanAutoPull("mistral")).To(BeFalse())
+
+		config.AutoPull = false
+		Expect(config.CanAutoPull("qwen3-coder:30b")).To(BeFalse())
+	})
+
+	It("should pull a missing model and answer with it", func() {
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Model: "qwen2.5-coder:7b", Message: "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Response).To(Equal("answer from qwen2.5-coder:7b"))
+		Expect(ollama.PullRequests()).To(HaveLen(1))
+		Expect(ollama.PullRequests()[0].Model).To(Equal("qwen2.5-coder:7b"))
+		Expect(ollama.ChatRequests()).To(HaveLen(2))
+	})
+
+	It("should refuse to pull models outside the allowlist", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Model: "mistral", Message: "hello"})
+		Expect(err).To(MatchError(ContainSubstring("auto-pull is not allowed for mistral")))
+		Expect(ollama.PullRequests()).To(BeEmpty())
+	})
+
+	It("should not pull when auto-pull is disabled", func() {
+		config.AutoPull = false
+
+		_, _, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{Message: "hello"})
+		Expect(err).To(MatchError(ContainSubstring("not found")))
+		Expect(ollama.PullRequests()).To(BeEmpty())
+	})
+
+	It("should report the pull progress to the caller", func() {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "test"}, nil)
+		mcp.AddTool(server, &mcp.Tool{Name: "chat"}, factory.ChatHandler())
+
+		var (
+			mu       sync.Mutex
+			messages []string
+			progress []float64
+		)
+		session := connectClient(server, &mcp.ClientOptions{
+			ProgressNotificationHandler: func(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+				mu.Lock()
+				defer mu.Unlock()
+				messages = append(messages, req.Params.Message)
+				progress = append(progress, req.Params.Progress)
+			},
+		})
+		defer func() { _ = session.Close() }()
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Meta:      mcp.Meta{"progressToken": "token"},
+			Name:      "chat",
+			Arguments: map[string]any{"model": "llama3.2", "message": "hi"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.IsError).To(BeFalse())
+
+		Eventually(func() string {
+			mu.Lock()
+			defer mu.Unlock()
+			return strings.Join(messages, "\n")
+		}).Should(ContainSubstring("pulling llama3.2: success (100%)"))
+
+		// The pull and the chat that follows share a single increasing progress
+		Eventually(func() []float64 {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]float64(nil), progress...)
+		}).Should(Equal([]float64{1, 2, 3, 4, 5, 6, 7}))
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(messages[1:5]).To(Equal([]string{
+			"pulling llama3.2: pulling a (50%)",
+			"pulling llama3.2: pulling a (100%)",
+			"pulling llama3.2: pulling b (25%)",
+			"pulling llama3.2: pulling b (100%)",
+		}))
+		Expect(messages[6]).To(Equal("answer from llama3.2"))
+	})
+})
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+// Batch limits
+const (
+	// DefaultBatchConcurrency is the number of batch items sent to Ollama at the same time
+	DefaultBatchConcurrency = 4
+
+	// maxBatchItems bounds the number of prompts of a single chat-batch call
+	maxBatchItems = 1000
+)
+
+// BatchItem is a single prompt of a chat-batch call
+type BatchItem struct {
+	Message      string         `json:"message" jsonschema:"the message to send to the model"`
+	Model        string         `json:"model,omitempty" jsonschema:"the Ollama model for this item, overriding the batch model (optional)"`
+	SystemPrompt string         `json:"system_prompt,omitempty" jsonschema:"system prompt for this item, overriding the batch system prompt (optional)"`
+	Options      map[string]any `json:"options,omitempty" jsonschema:"model options for this item, merged over the batch options (optional)"`
+}
+
+// ChatBatchInput represents the input for the chat-batch tool
+type ChatBatchInput struct {
+	Items        []BatchItem    `json:"items" jsonschema:"the prompts to run"`
+	Model        string         `json:"model,omitempty" jsonschema:"the Ollama model used by items without their own, defaults to the chat model (optional)"`
+	SystemPrompt string         `json:"system_prompt,omitempty" jsonschema:"system prompt used by items without their own (optional)"`
+	Options      map[string]any `json:"options,omitempty" jsonschema:"model options shared by all items (optional)"`
+	Format       any            `json:"format,omitempty" jsonschema:"\"json\" or a JSON Schema object every reply must follow (optional)"`
+	Concurrency  int            `json:"concurrency,omitempty" jsonschema:"number of items run at the same time, capped by the server setting (optional)"`
+}
+
+// BatchResult is the outcome of a single batch item
+type BatchResult struct {
+	Index           int    `json:"index" jsonschema:"position of the item in the batch"`
+	Model           string `json:"model,omitempty" jsonschema:"the model that answered"`
+	Response        string `json:"response,omitempty" jsonschema:"the response from the model"`
+	Structured      any    `json:"structured,omitempty" jsonschema:"the parsed JSON reply when a format was requested"`
+	Usage           *Usage `json:"usage,omitempty" jsonschema:"token counts, speed and timings of the generation"`
+	Truncated       bool   `json:"truncated,omitempty" jsonschema:"true when the response is incomplete"`
+	TruncatedReason string `json:"truncated_reason,omitempty" jsonschema:"why the response is incomplete: timeout, cancelled or length"`
+	Cached          bool   `json:"cached,omitempty" jsonschema:"true when the response was served from the response cache"`
+	Error           string `json:"error,omitempty" jsonschema:"why the item failed"`
+}
+
+// ChatBatchOutput represents the output from the chat-batch tool
+type ChatBatchOutput struct {
+	Results   []BatchResult `json:"results" jsonschema:"one result per item, in input order"`
+	Succeeded int           `json:"succeeded" jsonschema:"number of items that were answered"`
+	Failed    int           `json:"failed" jsonschema:"number of items that failed"`
+	Usage     *Usage        `json:"usage,omitempty" jsonschema:"token counts of all answered items combined"`
+}
+
+// runWorkers calls fn for every index in [0, count) using at most workers goroutines
+// and waits for all calls to return. Indexes not yet started are skipped once ctx is done.
+func runWorkers(ctx context.Context, workers, count int, fn func(i int)) {
+	workers = max(1, min(workers, count))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,113 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Chat batch", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			message := req.Messages[len(req.Messages)-1].Content
+			return []api.ChatResponse{{
+				Message: api.Message{Role: "assistant", Content: req.Model + ":" + message},
+				Done:    true,
+				Metrics: api.Metrics{PromptEvalCount: 2, EvalCount: 3},
+			}}
+		}
+		config = &core.Config{
+			Client:           ollama.Client(),
+			ContextSize:      32000,
+			ChatModel:        "test-chat-model",
+			KeepAlive:        "1m",
+			BatchConcurrency: 3,
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should return the results in input order", func() {
+		ollama.firstDelay = 20 * time.Millisecond
+		var items []core.BatchItem
+		for i := range 10 {
+			items = append(items, core.BatchItem{Message: fmt.Sprintf("item %d", i)})
+		}
+
+		_, output, err := factory.ChatBatchHandler()(context.Background(), nil, core.ChatBatchInput{Items: items})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Results).To(HaveLen(10))
+		for i, result := range output.Results {
+			Expect(result.Index).To(Equal(i))
+			Expect(result.Response).To(Equal(fmt.Sprintf("test-chat-model:item %d", i)))
+		}
+		Expect(output.Succeeded).To(Equal(10))
+		Expect(output.Usage.TotalTokens).To(Equal(50))
+		Expect(ollama.peakChats.Load()).To(BeEquivalentTo(3))
+	})
+
+	It("should let the caller lower the concurrency", func() {
+		ollama.firstDelay = 20 * time.Millisecond
+		items := []core.BatchItem{{Message: "a"}, {Message: "b"}, {Message: "c"}}
+
+		_, _, err := factory.ChatBatchHandler()(context.Background(), nil, core.ChatBatchInput{Items: items, Concurrency: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ollama.peakChats.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should apply per-item models and options over the batch defaults", func() {
+		_, output, err := factory.ChatBatchHandler()(context.Background(), nil, core.ChatBatchInput{
+			Model:        "batch-model",
+			SystemPrompt: "Classify the snippet",
+			Options:      map[string]any{"temperature": 0, "seed": 1},
+			Items: []core.BatchItem{
+				{Message: "a"},
+				{Message: "b", Model: "item-model", Options: map[string]any{"seed": 2}},
+			},
+			Concurrency: 1,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Results[0].Model).To(Equal("batch-model"))
+		Expect(output.Results[1].Model).To(Equal("item-model"))
+
+		requests := ollama.ChatRequests()
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[0].Messages[0].Content).To(Equal("Classify the snippet"))
+		Expect(requests[0].Options).To(HaveKeyWithValue("seed", BeNumerically("==", 1)))
+		Expect(requests[1].Options).To(HaveKeyWithValue("seed", BeNumerically("==", 2)))
+		Expect(requests[1].Options).To(HaveKeyWithValue("temperature", BeNumerically("==", 0)))
+	})
+
+	It("should report per-item errors without failing the batch", func() {
+		_, output, err := factory.ChatBatchHandler()(context.Background(), nil, core.ChatBatchInput{
+			Items: []core.BatchItem{{Message: "a"}, {Message: "b", Model: "../bad"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Results[0].Error).To(BeEmpty())
+		Expect(output.Results[1].Error).To(ContainSubstring("invalid model name"))
+		Expect(output.Succeeded).To(Equal(1))
+		Expect(output.Failed).To(Equal(1))
+	})
+
+	It("should reject an empty batch", func() {
+		_, _, err := factory.ChatBatchHandler()(context.Background(), nil, core.ChatBatchInput{})
+		Expect(err).To(MatchError(ContainSubstring("items cannot be empty")))
+	})
+})
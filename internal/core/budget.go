@@ -0,0 +1,267 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Context strategies, applied when a prompt does not fit in the context size
+const (
+	// ContextStrategyRaise raises num_ctx up to the model's maximum context length
+	ContextStrategyRaise = "raise"
+	// ContextStrategyReject rejects the request with an error
+	ContextStrategyReject = "reject"
+	// ContextStrategyTrim leaves out the oldest session messages
+	ContextStrategyTrim = "trim"
+	// ContextStrategyNone sends the request unchanged, letting Ollama truncate the prompt
+	ContextStrategyNone = "none"
+
+	// DefaultContextStrategy is the strategy used when none is configured
+	DefaultContextStrategy = ContextStrategyRaise
+)
+
+// Token estimates
+const (
+	// charsPerToken approximates the length of a token in characters
+	charsPerToken = 4
+
+	// messageTokens approximates the tokens the chat template adds around each message
+	messageTokens = 4
+
+	// imageTokens approximates the tokens of an image for vision models
+	imageTokens = 768
+
+	// outputReserve is the room kept for the reply when num_predict is not set
+	outputReserve = 1024
+
+	// contextStep rounds raised context sizes up, so that Ollama reuses loaded models
+	contextStep = 1024
+)
+
+// ValidContextStrategy reports whether a context strategy is known
+func ValidContextStrategy(strategy string) bool {
+	switch strategy {
+	case "", ContextStrategyRaise, ContextStrategyReject, ContextStrategyTrim, ContextStrategyNone:
+		return true
+	}
+	return false
+}
+
+// estimateTokens approximates the number of prompt tokens of a chat request
+func estimateTokens(request *api.ChatRequest) int {
+	chars := 0
+	tokens := 0
+	for _, message := range request.Messages {
+		chars += len(message.Content) + len(message.Thinking)
+		for _, call := range message.ToolCalls {
+			chars += len(call.Function.Name) + len(call.Function.Arguments.String())
+		}
+		tokens += messageTokens + imageTokens*len(message.Images)
+	}
+	if len(request.Tools) > 0 {
+		if data, err := json.Marshal(request.Tools); err == nil {
+			chars += len(data)
+		}
+	}
+	return tokens + (chars+charsPerToken-1)/charsPerToken
+}
+
+// intOption returns an integer model option, whether it was set from Go or decoded from JSON
+func intOption(options map[string]any, key string) (int, bool) {
+	return toInt(options[key])
+}
+
+// toInt converts a number to an int
+func toInt(value any) (int, bool) {
+	switch value := value.(type) {
+	case int:
+		return value, true
+	case int32:
+		return int(value), true
+	case int64:
+		return int(value), true
+	case float32:
+		return int(value), true
+	case float64:
+		return int(value), true
+	case json.Number:
+		n, err := value.Int64()
+		return int(n), err == nil
+	}
+	return 0, false
+}
+
+// contextLengthCache remembers the maximum context length of the models looked up
+type contextLengthCache struct {
+	mu      sync.Mutex
+	lengths map[string]int
+}
+
+// newContextLengthCache creates an empty context length cache
+func newContextLengthCache() *contextLengthCache {
+	return &contextLengthCache{lengths: make(map[string]int)}
+}
+
+// ParseModelContextSizes parses a comma-separated list of model=size pairs,
+// such as "qwen3-coder:30b=65536,llama3.2=8192"
+func ParseModelContextSizes(spec string) (map[string]int, error) {
+	sizes := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		model, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid context size %q: expected model=size", entry)
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid context size for %s: %s", strings.TrimSpace(model), value)
+		}
+		sizes[withDefaultTag(strings.TrimSpace(model))] = size
+	}
+	return sizes, nil
+}
+
+// modelContextLength returns the maximum context length of a model, read from the
+// <architecture>.context_length entry of its model info. Lengths are cached, while
+// failed lookups are retried on the next call.
+func (h *HandlerFactory) modelContextLength(ctx context.Context, config *Config, model string) (int, error) {
+	cache := h.server.contextLengths
+	cache.mu.Lock()
+	length, ok := cache.lengths[withDefaultTag(model)]
+	cache.mu.Unlock()
+	if ok {
+		return length, nil
+	}
+
+	var response *api.ShowResponse
+	err := config.Retry.do(ctx, func() error {
+		var err error
+		response, err = config.Client.Show(ctx, &api.ShowRequest{Model: model})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for key, value := range response.ModelInfo {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if length, ok := toInt(value); ok && length > 0 {
+			cache.mu.Lock()
+			cache.lengths[withDefaultTag(model)] = length
+			cache.mu.Unlock()
+			return length, nil
+		}
+	}
+	return 0, fmt.Errorf("model %s does not report its context length", model)
+}
+
+// defaultContextSize returns the num_ctx used for a model when the caller sets none: the
+// configured size of the model, or else its maximum context length capped by the global
+// context size, which also applies when the maximum is unknown
+func (h *HandlerFactory) defaultContextSize(ctx context.Context, config *Config, model string) int {
+	if size, ok := config.ModelContextSizes[withDefaultTag(model)]; ok {
+		return size
+	}
+
+	length, err := h.modelContextLength(ctx, config, model)
+	if err != nil || config.ContextSize > 0 && length > config.ContextSize {
+		return config.ContextSize
+	}
+	return length
+}
+
+// fitContext makes a chat request fit in its context size according to the configured
+// strategy, and returns the number of messages left out. The prompt size is estimated,
+// keeping room for the reply. A context size set by the caller is never raised: prompts
+// that do not fit in it are rejected instead.
+func (h *HandlerFactory) fitContext(ctx context.Context, config *Config, request *api.ChatRequest, explicit bool) (int, error) {
+	strategy := config.ContextStrategy
+	if strategy == "" {
+		strategy = DefaultContextStrategy
+	}
+	numCtx, ok := intOption(request.Options, "num_ctx")
+	if strategy == ContextStrategyNone || !ok || numCtx <= 0 {
+		return 0, nil
+	}
+
+	reserve := outputReserve
+	if numPredict, ok := intOption(request.Options, "num_predict"); ok && numPredict > 0 {
+		reserve = numPredict
+	}
+	needed := estimateTokens(request) + reserve
+	if needed <= numCtx {
+		return 0, nil
+	}
+
+	switch {
+	case strategy == ContextStrategyReject:
+		return 0, fmt.Errorf("the prompt needs about %d tokens including %d for the reply, more than the context size of %d tokens", needed, reserve, numCtx)
+
+	case strategy == ContextStrategyRaise && explicit:
+		return 0, fmt.Errorf("the prompt needs about %d tokens including %d for the reply, more than the requested context size of %d tokens", needed, reserve, numCtx)
+
+	case strategy == ContextStrategyTrim:
+		trimmed := trimMessages(request, numCtx-reserve)
+		if needed = estimateTokens(request) + reserve; needed > numCtx {
+			return trimmed, fmt.Errorf("the prompt needs about %d tokens including %d for the reply even without the older messages, more than the context size of %d tokens", needed, reserve, numCtx)
+		}
+		return trimmed, nil
+
+	default:
+		// Without the model's maximum, leave num_ctx as requested
+		maxCtx, err := h.modelContextLength(ctx, config, request.Model)
+		if err != nil {
+			return 0, nil
+		}
+		if needed > maxCtx {
+			return 0, fmt.Errorf("the prompt needs about %d tokens including %d for the reply, more than the maximum context length of %d tokens of %s", needed, reserve, maxCtx, request.Model)
+		}
+		request.Options["num_ctx"] = min(maxCtx, (needed+contextStep-1)/contextStep*contextStep)
+		return 0, nil
+	}
+}
+
+// trimMessages leaves out the oldest conversation turns until the prompt fits in the given
+// number of tokens. System messages and the last turn are always kept, and a turn is
+// removed as a whole so that no tool result is separated from its call.
+func trimMessages(request *api.ChatRequest, tokens int) int {
+	trimmed := 0
+	for estimateTokens(request) > tokens {
+		// The oldest turn starts at the first message that is not a system message
+		start := -1
+		for i, message := range request.Messages {
+			if message.Role != "system" {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			break
+		}
+
+		// and ends before the next user message
+		end := start + 1
+		for end < len(request.Messages) && request.Messages[end].Role != "user" {
+			end++
+		}
+		if end >= len(request.Messages) {
+			break
+		}
+
+		request.Messages = append(request.Messages[:start:start], request.Messages[end:]...)
+		trimmed += end - start
+	}
+	return trimmed
+}
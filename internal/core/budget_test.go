@@ -0,0 +1,234 @@
+package core_test
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Context window", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+	)
+
+	// longMessage is about 2000 tokens, which does not fit in 2048 tokens with the reply
+	longMessage := strings.Repeat("word ", 1600)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.models["test-chat-model"] = &api.ShowResponse{ModelInfo: map[string]any{
+			"general.architecture": "llama",
+			"llama.context_length": 131072,
+		}}
+		config = &core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 2048,
+			ChatModel:   "test-chat-model",
+			KeepAlive:   "1m",
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	sentContextSize := func() any {
+		requests := ollama.ChatRequests()
+		return requests[len(requests)-1].Options["num_ctx"]
+	}
+
+	It("should leave the context size alone when the prompt fits", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentContextSize()).To(BeNumerically("==", 2048))
+	})
+
+	It("should raise the context size up to the model's maximum", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: longMessage})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentContextSize()).To(BeNumerically("==", 3072))
+	})
+
+	It("should reject prompts longer than the model's maximum", func() {
+		ollama.models["test-chat-model"].ModelInfo["llama.context_length"] = 2500
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: longMessage})
+		Expect(err).To(MatchError(ContainSubstring("maximum context length of 2500 tokens")))
+		Expect(ollama.ChatRequests()).To(BeEmpty())
+	})
+
+	It("should not raise a context size set by the caller", func() {
+		contextSize := 2048
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: longMessage, ContextSize: &contextSize})
+		Expect(err).To(MatchError(ContainSubstring("more than the requested context size of 2048 tokens")))
+		Expect(ollama.ChatRequests()).To(BeEmpty())
+	})
+
+	It("should try the next model when the prompt does not fit", func() {
+		ollama.models["test-chat-model"].ModelInfo["llama.context_length"] = 2500
+		ollama.models["large-model"] = &api.ShowResponse{ModelInfo: map[string]any{"llama.context_length": 131072}}
+		config.FallbackModels = map[string][]string{"chat": {"large-model"}}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: longMessage})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Model).To(Equal("large-model"))
+		Expect(ollama.ChatRequests()).To(HaveLen(1))
+		Expect(sentContextSize()).To(BeNumerically("==", 3072))
+	})
+
+	It("should fit the messages of a format retry", func() {
+		config.FormatRetries = 1
+		replies := []string{strings.Repeat("x", 1600), `{"answer": 1}`}
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			reply := replies[0]
+			replies = replies[1:]
+			return []api.ChatResponse{{Message: api.Message{Role: "assistant", Content: reply}, Done: true}}
+		}
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: strings.Repeat("word ", 700), Format: "json"})
+		Expect(err).NotTo(HaveOccurred())
+
+		requests := ollama.ChatRequests()
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[0].Options["num_ctx"]).To(BeNumerically("==", 2048))
+		Expect(requests[1].Options["num_ctx"]).To(BeNumerically("==", 3072))
+	})
+
+	It("should keep the context size when the model's maximum is unknown", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Model: "other-model", Message: longMessage})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentContextSize()).To(BeNumerically("==", 2048))
+	})
+
+	It("should reject prompts that do not fit with the reject strategy", func() {
+		config.ContextStrategy = core.ContextStrategyReject
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: longMessage})
+		Expect(err).To(MatchError(ContainSubstring("more than the context size of 2048 tokens")))
+		Expect(ollama.ChatRequests()).To(BeEmpty())
+	})
+
+	It("should leave out the oldest session messages with the trim strategy", func() {
+		config.ContextStrategy = core.ContextStrategyTrim
+		session, err := factory.GetServer().GetSessions().Create("be brief")
+		Expect(err).NotTo(HaveOccurred())
+		turn := strings.Repeat("x", 1200)
+		for range 3 {
+			Expect(factory.GetServer().GetSessions().Append(session.ID,
+				api.Message{Role: "user", Content: turn},
+				api.Message{Role: "assistant", Content: turn},
+			)).To(Succeed())
+		}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{SessionID: session.ID, Message: "and now?"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.TrimmedMessages).To(Equal(4))
+
+		messages := ollama.ChatRequests()[0].Messages
+		Expect(messages).To(HaveLen(4))
+		Expect(messages[0].Content).To(Equal("be brief"))
+		Expect(messages[1].Role).To(Equal("user"))
+		Expect(messages[3].Content).To(Equal("and now?"))
+		Expect(sentContextSize()).To(BeNumerically("==", 2048))
+
+		// The session keeps its whole history
+		session, err = factory.GetServer().GetSessions().Get(session.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(session.Messages).To(HaveLen(9))
+	})
+
+	It("should fail when the last message alone does not fit with the trim strategy", func() {
+		config.ContextStrategy = core.ContextStrategyTrim
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: longMessage})
+		Expect(err).To(MatchError(ContainSubstring("even without the older messages")))
+	})
+})
+
+var _ = Describe("Default context size", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.models["small-model"] = &api.ShowResponse{ModelInfo: map[string]any{"llama.context_length": 8192}}
+		ollama.models["large-model"] = &api.ShowResponse{ModelInfo: map[string]any{"qwen3.context_length": 262144}}
+		config = &core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32768,
+			ChatModel:   "large-model",
+			CodeModel:   "small-model",
+			KeepAlive:   "1m",
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	chatContextSize := func(input core.ChatInput) any {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, input)
+		Expect(err).NotTo(HaveOccurred())
+		requests := ollama.ChatRequests()
+		return requests[len(requests)-1].Options["num_ctx"]
+	}
+
+	It("should parse context sizes per model", func() {
+		sizes, err := core.ParseModelContextSizes("qwen3-coder:30b=65536, llama3.2=8192")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sizes).To(Equal(map[string]int{"qwen3-coder:30b": 65536, "llama3.2:latest": 8192}))
+
+		_, err = core.ParseModelContextSizes("llama3.2")
+		Expect(err).To(HaveOccurred())
+		_, err = core.ParseModelContextSizes("llama3.2=0")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should use the model's maximum context length when it is smaller", func() {
+		Expect(chatContextSize(core.ChatInput{Model: "small-model", Message: "hello"})).To(BeNumerically("==", 8192))
+	})
+
+	It("should cap the model's maximum context length", func() {
+		Expect(chatContextSize(core.ChatInput{Message: "hello"})).To(BeNumerically("==", 32768))
+	})
+
+	It("should prefer the configured size of the model", func() {
+		config.ModelContextSizes = map[string]int{"large-model:latest": 65536}
+		Expect(chatContextSize(core.ChatInput{Message: "hello"})).To(BeNumerically("==", 65536))
+	})
+
+	It("should keep an explicit context size", func() {
+		size := 4096
+		Expect(chatContextSize(core.ChatInput{Model: "small-model", Message: "hello", ContextSize: &size})).To(BeNumerically("==", 4096))
+	})
+
+	It("should fall back to the global size for unknown models", func() {
+		Expect(chatContextSize(core.ChatInput{Model: "other-model", Message: "hello"})).To(BeNumerically("==", 32768))
+	})
+
+	It("should look the context length up once per model", func() {
+		chatContextSize(core.ChatInput{Message: "hello"})
+		chatContextSize(core.ChatInput{Message: "again"})
+		Expect(ollama.shows.Load()).To(BeNumerically("==", 1))
+	})
+
+	It("should resolve the context size of completions", func() {
+		_, _, err := factory.CompleteHandler()(context.Background(), nil, core.CompleteInput{Prompt: "func add("})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ollama.GenerateRequests()[0].Options["num_ctx"]).To(BeNumerically("==", 8192))
+	})
+})
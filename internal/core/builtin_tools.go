@@ -0,0 +1,229 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// maxBuiltinFileBytes limits how much of a file the read_file tool returns
+const maxBuiltinFileBytes = 64 * 1024
+
+// builtinTool is a tool the server can execute on behalf of the model
+type builtinTool struct {
+	description string
+	parameters  api.ToolFunctionParameters
+	run         func(ctx context.Context, config *Config, args api.ToolCallFunctionArguments) (string, error)
+}
+
+// builtinTools lists the sandboxed tools available to the agent loop
+var builtinTools = map[string]builtinTool{
+	"current_time": {
+		description: "Get the current date and time in RFC 3339 format",
+		parameters: api.ToolFunctionParameters{
+			Type:       "object",
+			Properties: map[string]api.ToolProperty{},
+		},
+		run: runCurrentTime,
+	},
+	"calculator": {
+		description: "Evaluate an arithmetic expression using + - * / % and parentheses",
+		parameters: api.ToolFunctionParameters{
+			Type:     "object",
+			Required: []string{"expression"},
+			Properties: map[string]api.ToolProperty{
+				"expression": {Type: api.PropertyType{"string"}, Description: "the expression to evaluate, for example (2 + 3) * 4"},
+			},
+		},
+		run: runCalculator,
+	},
+	"read_file": {
+		description: "Read a text file from the workspace",
+		parameters: api.ToolFunctionParameters{
+			Type:     "object",
+			Required: []string{"path"},
+			Properties: map[string]api.ToolProperty{
+				"path": {Type: api.PropertyType{"string"}, Description: "path of the file, relative to the workspace root"},
+			},
+		},
+		run: runReadFile,
+	},
+	"list_directory": {
+		description: "List the entries of a directory in the workspace",
+		parameters: api.ToolFunctionParameters{
+			Type:     "object",
+			Required: []string{"path"},
+			Properties: map[string]api.ToolProperty{
+				"path": {Type: api.PropertyType{"string"}, Description: "path of the directory, relative to the workspace root"},
+			},
+		},
+		run: runListDirectory,
+	},
+}
+
+// BuiltinToolNames returns the names of the tools the server can execute, sorted
+func BuiltinToolNames() []string {
+	names := make([]string, 0, len(builtinTools))
+	for name := range builtinTools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// apiTool converts a built-in tool to its Ollama definition
+func (t builtinTool) apiTool(name string) api.Tool {
+	return api.Tool{
+		Type: "function",
+		Function: api.ToolFunction{
+			Name:        name,
+			Description: t.description,
+			Parameters:  t.parameters,
+		},
+	}
+}
+
+func runCurrentTime(ctx context.Context, config *Config, args api.ToolCallFunctionArguments) (string, error) {
+	return time.Now().Format(time.RFC3339), nil
+}
+
+func runCalculator(ctx context.Context, config *Config, args api.ToolCallFunctionArguments) (string, error) {
+	expression, err := stringArgument(args, "expression")
+	if err != nil {
+		return "", err
+	}
+
+	expr, err := parser.ParseExpr(expression)
+	if err != nil {
+		return "", fmt.Errorf("invalid expression: %w", err)
+	}
+	value, err := evalConstant(expr)
+	if err != nil {
+		return "", err
+	}
+	return value.ExactString(), nil
+}
+
+// evalConstant evaluates an arithmetic expression made only of number literals
+func evalConstant(expr ast.Expr) (constant.Value, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.INT && e.Kind != token.FLOAT {
+			return nil, fmt.Errorf("unsupported literal: %s", e.Value)
+		}
+		return constant.MakeFromLiteral(e.Value, e.Kind, 0), nil
+	case *ast.ParenExpr:
+		return evalConstant(e.X)
+	case *ast.UnaryExpr:
+		x, err := evalConstant(e.X)
+		if err != nil {
+			return nil, err
+		}
+		if e.Op != token.ADD && e.Op != token.SUB {
+			return nil, fmt.Errorf("unsupported operator: %s", e.Op)
+		}
+		return constant.UnaryOp(e.Op, x, 0), nil
+	case *ast.BinaryExpr:
+		x, err := evalConstant(e.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := evalConstant(e.Y)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Op {
+		case token.ADD, token.SUB, token.MUL:
+			return constant.BinaryOp(x, e.Op, y), nil
+		case token.QUO:
+			if constant.Sign(y) == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			// Use exact division so that 7/2 is 3.5 rather than 3
+			return constant.BinaryOp(constant.ToFloat(x), token.QUO, constant.ToFloat(y)), nil
+		case token.REM:
+			if x.Kind() != constant.Int || y.Kind() != constant.Int {
+				return nil, fmt.Errorf("%% requires integer operands")
+			}
+			if constant.Sign(y) == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return constant.BinaryOp(x, token.REM, y), nil
+		default:
+			return nil, fmt.Errorf("unsupported operator: %s", e.Op)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported expression")
+	}
+}
+
+func runReadFile(ctx context.Context, config *Config, args api.ToolCallFunctionArguments) (string, error) {
+	path, err := stringArgument(args, "path")
+	if err != nil {
+		return "", err
+	}
+	resolved, err := resolveAllowedPath(config.AllowedRoots, path)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(resolved)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBuiltinFileBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxBuiltinFileBytes {
+		return string(data[:maxBuiltinFileBytes]) + "\n[truncated]", nil
+	}
+	return string(data), nil
+}
+
+func runListDirectory(ctx context.Context, config *Config, args api.ToolCallFunctionArguments) (string, error) {
+	path, err := stringArgument(args, "path")
+	if err != nil {
+		return "", err
+	}
+	resolved, err := resolveAllowedPath(config.AllowedRoots, path)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(resolved)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, entry := range entries {
+		b.WriteString(entry.Name())
+		if entry.IsDir() {
+			b.WriteString("/")
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// stringArgument extracts a required string argument from a tool call
+func stringArgument(args api.ToolCallFunctionArguments, name string) (string, error) {
+	value, ok := args[name].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("missing string argument %q", name)
+	}
+	return value, nil
+}
@@ -0,0 +1,291 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Response cache defaults
+const (
+	// DefaultCacheTTL is how long a cached response is reused
+	DefaultCacheTTL = 24 * time.Hour
+
+	// DefaultCacheMaxEntries bounds the number of cached responses
+	DefaultCacheMaxEntries = 1000
+)
+
+// ClearCacheInput represents the input for the cache-clear tool
+type ClearCacheInput struct{}
+
+// ClearCacheOutput represents the output from the cache-clear tool
+type ClearCacheOutput struct {
+	Removed int `json:"removed" jsonschema:"number of cached responses removed"`
+}
+
+// cacheEntry is a cached chat response, stored as a JSON file named after its key
+type cacheEntry struct {
+	Key       string           `json:"key"`
+	Response  api.ChatResponse `json:"response"`
+	CreatedAt time.Time        `json:"created_at"`
+
+	// lastUsed orders the entries for eviction, most recently used last
+	lastUsed time.Time
+}
+
+// ResponseCache keeps the responses of deterministic chat requests in memory, backed by a
+// directory so that they survive restarts. Entries expire after the TTL, and the least
+// recently used entries are evicted beyond the maximum number of entries.
+type ResponseCache struct {
+	mu         sync.Mutex
+	dir        string
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*cacheEntry
+	loaded     bool
+}
+
+// NewResponseCache creates a response cache stored in dir, or in memory only when dir is empty
+func NewResponseCache(dir string, ttl time.Duration, maxEntries int) *ResponseCache {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheMaxEntries
+	}
+	return &ResponseCache{
+		dir:        dir,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*cacheEntry),
+	}
+}
+
+// DefaultCacheDir returns the directory used to store cached responses by default
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ollama-mcp", "responses")
+}
+
+// Get returns the cached response for a key
+func (c *ResponseCache) Get(key string) (api.ChatResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return api.ChatResponse{}, false
+	}
+	if time.Since(entry.CreatedAt) > c.ttl {
+		c.remove(key)
+		return api.ChatResponse{}, false
+	}
+	entry.lastUsed = time.Now()
+	return entry.Response, true
+}
+
+// Put caches a response, evicting the least recently used entries beyond the limit
+func (c *ResponseCache) Put(key string, response api.ChatResponse) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+
+	now := time.Now()
+	entry := &cacheEntry{Key: key, Response: response, CreatedAt: now, lastUsed: now}
+	if err := c.write(entry); err != nil {
+		return err
+	}
+	c.entries[key] = entry
+
+	c.evict()
+	return nil
+}
+
+// Clear removes every cached response and returns the number removed
+func (c *ResponseCache) Clear() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+
+	removed := len(c.entries)
+	for key := range c.entries {
+		c.remove(key)
+	}
+	if c.dir == "" {
+		return removed, nil
+	}
+
+	// Remove the files the cache could not load as well
+	paths, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return removed, err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to clear the response cache: %w", err)
+		}
+	}
+	return removed, nil
+}
+
+// Len returns the number of cached responses
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	return len(c.entries)
+}
+
+// load reads the entries stored on disk the first time the cache is used. Expired and
+// unreadable entries are dropped.
+func (c *ResponseCache) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	if c.dir == "" {
+		return
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var entry cacheEntry
+		if err := json.Unmarshal(data, &entry); err != nil || entry.Key+".json" != filepath.Base(path) || time.Since(entry.CreatedAt) > c.ttl {
+			_ = os.Remove(path)
+			continue
+		}
+		entry.lastUsed = entry.CreatedAt
+		c.entries[entry.Key] = &entry
+	}
+	c.evict()
+}
+
+// evict removes the least recently used entries beyond the maximum number of entries
+func (c *ResponseCache) evict() {
+	for len(c.entries) > c.maxEntries {
+		var oldest *cacheEntry
+		for _, entry := range c.entries {
+			if oldest == nil || entry.lastUsed.Before(oldest.lastUsed) {
+				oldest = entry
+			}
+		}
+		c.remove(oldest.Key)
+	}
+}
+
+// remove deletes an entry from memory and disk
+func (c *ResponseCache) remove(key string) {
+	delete(c.entries, key)
+	if c.dir != "" {
+		_ = os.Remove(filepath.Join(c.dir, key+".json"))
+	}
+}
+
+// write stores an entry on disk, replacing it atomically
+func (c *ResponseCache) write(entry *cacheEntry) error {
+	if c.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create the response cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(c.dir, entry.Key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write the response cache: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write the response cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write the response cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, entry.Key+".json")); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write the response cache: %w", err)
+	}
+	return nil
+}
+
+// isDeterministic reports whether the options make the generation repeatable:
+// a temperature of 0 or a fixed seed
+func isDeterministic(options map[string]any) bool {
+	if _, ok := options["seed"]; ok {
+		return true
+	}
+
+	switch temperature := options["temperature"].(type) {
+	case float32:
+		return temperature == 0
+	case float64:
+		return temperature == 0
+	case int:
+		return temperature == 0
+	}
+	return false
+}
+
+// cacheKey identifies the response to a chat request on a given model build. Settings
+// that do not change the generated text, like keep_alive, are left out.
+func cacheKey(digest string, request *api.ChatRequest) (string, error) {
+	data, err := json.Marshal(struct {
+		Digest   string          `json:"digest"`
+		Messages []api.Message   `json:"messages"`
+		Format   json.RawMessage `json:"format,omitempty"`
+		Options  map[string]any  `json:"options"`
+		Tools    api.Tools       `json:"tools,omitempty"`
+		Think    *api.ThinkValue `json:"think,omitempty"`
+	}{digest, request.Messages, request.Format, request.Options, request.Tools, request.Think})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// modelDigest returns the digest of an installed model, so that cached responses are not
+// reused once the model is pulled again. Names without a tag refer to the latest tag.
+func modelDigest(ctx context.Context, config *Config, model string) (string, error) {
+	var response *api.ListResponse
+	err := config.Retry.do(ctx, func() error {
+		var err error
+		response, err = config.Client.List(ctx)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	name := withDefaultTag(model)
+	for _, installed := range response.Models {
+		if installed.Name == model || installed.Name == name || installed.Model == model || installed.Model == name {
+			return installed.Digest, nil
+		}
+	}
+	return "", fmt.Errorf("model not found: %s", model)
+}
@@ -0,0 +1,153 @@
+package core_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Response cache", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+	)
+
+	zero := float32(0)
+
+	chat := func(message string, temperature *float32) core.ChatOutput {
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: message, Temperature: temperature})
+		Expect(err).NotTo(HaveOccurred())
+		return output
+	}
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.models["test-chat-model"] = &api.ShowResponse{}
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{{
+				Message: api.Message{Role: "assistant", Content: "answer to " + req.Messages[len(req.Messages)-1].Content},
+				Done:    true,
+			}}
+		}
+		config = &core.Config{
+			Client:          ollama.Client(),
+			ContextSize:     32000,
+			ChatModel:       "test-chat-model",
+			KeepAlive:       "1m",
+			CacheEnabled:    true,
+			CacheDir:        GinkgoT().TempDir(),
+			CacheTTL:        time.Hour,
+			CacheMaxEntries: 10,
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should serve repeated deterministic requests from the cache", func() {
+		first := chat("hello", &zero)
+		Expect(first.Cached).To(BeFalse())
+
+		second := chat("hello", &zero)
+		Expect(second.Cached).To(BeTrue())
+		Expect(second.Response).To(Equal("answer to hello"))
+		Expect(ollama.ChatRequests()).To(HaveLen(1))
+	})
+
+	It("should flag cached answers of a model comparison", func() {
+		compare := func() core.ModelAnswer {
+			_, output, err := factory.CompareModelsHandler()(context.Background(), nil, core.CompareModelsInput{Models: []string{"test-chat-model"}, Message: "hello", Temperature: &zero})
+			Expect(err).NotTo(HaveOccurred())
+			return output.Answers[0]
+		}
+
+		Expect(compare().Cached).To(BeFalse())
+
+		answer := compare()
+		Expect(answer.Cached).To(BeTrue())
+		Expect(answer.LatencyMs).To(BeZero())
+		Expect(answer.Response).To(Equal("answer to hello"))
+		Expect(ollama.ChatRequests()).To(HaveLen(1))
+	})
+
+	It("should cache requests with a seed", func() {
+		for range 2 {
+			_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello", Options: map[string]any{"seed": 42}})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(ollama.ChatRequests()).To(HaveLen(1))
+	})
+
+	It("should not cache sampled requests", func() {
+		chat("hello", nil)
+		Expect(chat("hello", nil).Cached).To(BeFalse())
+		Expect(ollama.ChatRequests()).To(HaveLen(2))
+	})
+
+	It("should tell different requests apart", func() {
+		chat("hello", &zero)
+		Expect(chat("goodbye", &zero).Response).To(Equal("answer to goodbye"))
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello", Temperature: &zero, Format: "json"})
+		Expect(err).To(HaveOccurred())
+		Expect(output.Cached).To(BeFalse())
+		Expect(ollama.ChatRequests()).To(HaveLen(2 + 1 + config.FormatRetries))
+	})
+
+	It("should keep the cache across restarts", func() {
+		chat("hello", &zero)
+
+		factory = core.NewHandlerFactory(core.NewServer(config))
+		Expect(chat("hello", &zero).Cached).To(BeTrue())
+		Expect(ollama.ChatRequests()).To(HaveLen(1))
+	})
+
+	It("should expire entries after the TTL", func() {
+		config.CacheTTL = 10 * time.Millisecond
+		factory = core.NewHandlerFactory(core.NewServer(config))
+
+		chat("hello", &zero)
+		time.Sleep(20 * time.Millisecond)
+		Expect(chat("hello", &zero).Cached).To(BeFalse())
+	})
+
+	It("should evict the least recently used entries beyond the limit", func() {
+		config.CacheMaxEntries = 2
+		factory = core.NewHandlerFactory(core.NewServer(config))
+
+		chat("one", &zero)
+		chat("two", &zero)
+		chat("one", &zero)
+		chat("three", &zero)
+		Expect(chat("one", &zero).Cached).To(BeTrue())
+		Expect(chat("two", &zero).Cached).To(BeFalse())
+	})
+
+	It("should clear the cache", func() {
+		chat("one", &zero)
+		chat("two", &zero)
+
+		_, output, err := factory.ClearCacheHandler()(context.Background(), nil, core.ClearCacheInput{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Removed).To(Equal(2))
+		Expect(chat("one", &zero).Cached).To(BeFalse())
+	})
+
+	It("should report a disabled cache", func() {
+		config.CacheEnabled = false
+		factory = core.NewHandlerFactory(core.NewServer(config))
+
+		Expect(chat("hello", &zero).Cached).To(BeFalse())
+		_, _, err := factory.ClearCacheHandler()(context.Background(), nil, core.ClearCacheInput{})
+		Expect(err).To(MatchError(ContainSubstring("disabled")))
+	})
+})
@@ -2,20 +2,72 @@ package core
 
 // ChatInput represents the input for chat operations
 type ChatInput struct {
-	Model        string         `json:"model" jsonschema:"the Ollama model to use for chat"`
-	Message      string         `json:"message" jsonschema:"the message to send to the model"`
-	ContextSize  *int           `json:"context_size,omitempty" jsonschema:"maximum context size in tokens (optional)"`
-	Temperature  *float32       `json:"temperature,omitempty" jsonschema:"controls randomness (0.0 to 1.0, optional)"`
-	TopP         *float32       `json:"top_p,omitempty" jsonschema:"controls diversity via nucleus sampling (0.0 to 1.0, optional)"`
-	TopK         *int           `json:"top_k,omitempty" jsonschema:"controls diversity via top-k sampling (optional)"`
-	SystemPrompt string         `json:"system_prompt,omitempty" jsonschema:"system prompt to use (optional)"`
-	Options      map[string]any `json:"options,omitempty" jsonschema:"additional model options (optional)"`
-	ToolName     string         `json:"tool_name,omitempty" jsonschema:"name of the tool being used (optional)"`
-	KeepAlive    *string        `json:"keep_alive,omitempty" jsonschema:"duration to keep the model loaded in memory (optional)"`
+	Model         string           `json:"model" jsonschema:"the Ollama model to use for chat"`
+	Message       string           `json:"message,omitempty" jsonschema:"the message to send to the model, optional when sending tool_results"`
+	ContextSize   *int             `json:"context_size,omitempty" jsonschema:"maximum context size in tokens (optional)"`
+	Temperature   *float32         `json:"temperature,omitempty" jsonschema:"controls randomness (0.0 to 1.0, optional)"`
+	TopP          *float32         `json:"top_p,omitempty" jsonschema:"controls diversity via nucleus sampling (0.0 to 1.0, optional)"`
+	TopK          *int             `json:"top_k,omitempty" jsonschema:"controls diversity via top-k sampling (optional)"`
+	MinP          *float32         `json:"min_p,omitempty" jsonschema:"minimum probability of a token relative to the most likely one (0.0 to 1.0, optional)"`
+	Seed          *int             `json:"seed,omitempty" jsonschema:"random seed, making replies reproducible (optional)"`
+	NumPredict    *int             `json:"num_predict,omitempty" jsonschema:"maximum number of tokens to generate, -1 for no limit (optional)"`
+	Stop          []string         `json:"stop,omitempty" jsonschema:"sequences that end the reply (optional)"`
+	RepeatPenalty *float32         `json:"repeat_penalty,omitempty" jsonschema:"penalizes repetitions, 1.0 to disable (optional)"`
+	SystemPrompt  string           `json:"system_prompt,omitempty" jsonschema:"system prompt to use (optional)"`
+	Options       map[string]any   `json:"options,omitempty" jsonschema:"additional Ollama model options, such as num_batch or presence_penalty (optional)"`
+	ToolName      string           `json:"tool_name,omitempty" jsonschema:"name of the tool being used (optional)"`
+	KeepAlive     *string          `json:"keep_alive,omitempty" jsonschema:"duration to keep the model loaded in memory (optional)"`
+	SessionID     string           `json:"session_id,omitempty" jsonschema:"session to continue, created with create-session (optional)"`
+	Think         any              `json:"think,omitempty" jsonschema:"enable reasoning with true or false, or set its level to low, medium or high (optional)"`
+	Format        any              `json:"format,omitempty" jsonschema:"\"json\" or a JSON Schema object the reply must follow (optional)"`
+	Tools         []ToolDefinition `json:"tools,omitempty" jsonschema:"functions the model may call; calls are returned in tool_calls (optional)"`
+	ToolResults   []ToolResult     `json:"tool_results,omitempty" jsonschema:"results of the tool_calls of the previous reply in the session, sent back to the model; the message may then be empty (optional)"`
+	Images        []ImageInput     `json:"images,omitempty" jsonschema:"images for vision models, as base64 data, MCP image content or local file paths (optional)"`
+	Files         []FileInput      `json:"files,omitempty" jsonschema:"local text files inlined before the message, optionally limited to a line range (optional)"`
+	BuiltinTools  []string         `json:"builtin_tools,omitempty" jsonschema:"built-in tools the server executes for the model until it answers: calculator, current_time, list_directory, read_file (optional)"`
+}
+
+// ToolDefinition describes a function the model can call
+type ToolDefinition struct {
+	Name        string         `json:"name" jsonschema:"name of the function"`
+	Description string         `json:"description,omitempty" jsonschema:"what the function does"`
+	Parameters  map[string]any `json:"parameters,omitempty" jsonschema:"JSON Schema object describing the function arguments"`
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	Name      string         `json:"name" jsonschema:"name of the function to call"`
+	Arguments map[string]any `json:"arguments" jsonschema:"arguments of the call"`
+}
+
+// ToolResult is the result of a tool call of the model, executed by the caller
+type ToolResult struct {
+	Name    string `json:"name" jsonschema:"name of the function that was called"`
+	Content string `json:"content" jsonschema:"result of the call"`
+}
+
+// ToolExecution records a built-in tool call executed by the server
+type ToolExecution struct {
+	Name      string         `json:"name" jsonschema:"name of the built-in tool"`
+	Arguments map[string]any `json:"arguments" jsonschema:"arguments of the call"`
+	Result    string         `json:"result,omitempty" jsonschema:"result returned to the model"`
+	Error     string         `json:"error,omitempty" jsonschema:"error returned to the model"`
 }
 
 type ChatOutput struct {
-	Response string `json:"response" jsonschema:"the response from the model"`
+	Model      string `json:"model,omitempty" jsonschema:"the model that answered"`
+	Response   string `json:"response" jsonschema:"the response from the model"`
+	Thinking   string `json:"thinking,omitempty" jsonschema:"the reasoning of the model, separate from the response"`
+	Usage      *Usage `json:"usage,omitempty" jsonschema:"token counts, speed and timings of the generation"`
+	Structured any    `json:"structured,omitempty" jsonschema:"the parsed JSON reply when a format was requested"`
+	SessionID  string `json:"session_id,omitempty" jsonschema:"the session the exchange was recorded in"`
+
+	ToolCalls       []ToolCall      `json:"tool_calls,omitempty" jsonschema:"function calls requested by the model"`
+	ToolExecutions  []ToolExecution `json:"tool_executions,omitempty" jsonschema:"built-in tool calls executed by the server"`
+	Truncated       bool            `json:"truncated,omitempty" jsonschema:"true when the response is incomplete"`
+	TruncatedReason string          `json:"truncated_reason,omitempty" jsonschema:"why the response is incomplete: timeout, cancelled or length"`
+	Cached          bool            `json:"cached,omitempty" jsonschema:"true when the response was served from the response cache"`
+	TrimmedMessages int             `json:"trimmed_messages,omitempty" jsonschema:"number of older session messages left out to fit the context window"`
 }
 
 // Note: ChatWithOllama is deprecated. Use HandlerFactory.ChatHandler() instead.
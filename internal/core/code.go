@@ -2,15 +2,30 @@ package core
 
 // CodeInput represents the input for code generation operations
 type CodeInput struct {
-	Message      string         `json:"message" jsonschema:"the message to send to the model"`
-	ContextSize  *int           `json:"context_size,omitempty" jsonschema:"maximum context size in tokens (optional)"`
-	SystemPrompt string         `json:"system_prompt,omitempty" jsonschema:"system prompt to use (optional)"`
-	Options      map[string]any `json:"options,omitempty" jsonschema:"additional model options (optional)"`
-	KeepAlive    *string        `json:"keep_alive,omitempty" jsonschema:"duration to keep the model loaded in memory (optional)"`
+	Message       string         `json:"message" jsonschema:"the message to send to the model"`
+	ContextSize   *int           `json:"context_size,omitempty" jsonschema:"maximum context size in tokens (optional)"`
+	SystemPrompt  string         `json:"system_prompt,omitempty" jsonschema:"system prompt to use (optional)"`
+	Seed          *int           `json:"seed,omitempty" jsonschema:"random seed, making replies reproducible (optional)"`
+	NumPredict    *int           `json:"num_predict,omitempty" jsonschema:"maximum number of tokens to generate, -1 for no limit (optional)"`
+	Stop          []string       `json:"stop,omitempty" jsonschema:"sequences that end the reply (optional)"`
+	RepeatPenalty *float32       `json:"repeat_penalty,omitempty" jsonschema:"penalizes repetitions, 1.0 to disable (optional)"`
+	MinP          *float32       `json:"min_p,omitempty" jsonschema:"minimum probability of a token relative to the most likely one (0.0 to 1.0, optional)"`
+	Options       map[string]any `json:"options,omitempty" jsonschema:"additional Ollama model options, such as num_batch or presence_penalty (optional)"`
+	KeepAlive     *string        `json:"keep_alive,omitempty" jsonschema:"duration to keep the model loaded in memory (optional)"`
+	SessionID     string         `json:"session_id,omitempty" jsonschema:"session to continue, created with create-session (optional)"`
+	Files         []FileInput    `json:"files,omitempty" jsonschema:"local text files inlined before the message, optionally limited to a line range (optional)"`
 }
 
 type CodeOutput struct {
-	Response string `json:"response" jsonschema:"the response from the model"`
+	Model           string `json:"model,omitempty" jsonschema:"the model that answered"`
+	Response        string `json:"response" jsonschema:"the response from the model"`
+	Thinking        string `json:"thinking,omitempty" jsonschema:"the reasoning of the model, separate from the response"`
+	Usage           *Usage `json:"usage,omitempty" jsonschema:"token counts, speed and timings of the generation"`
+	SessionID       string `json:"session_id,omitempty" jsonschema:"the session the exchange was recorded in"`
+	Truncated       bool   `json:"truncated,omitempty" jsonschema:"true when the response is incomplete"`
+	TruncatedReason string `json:"truncated_reason,omitempty" jsonschema:"why the response is incomplete: timeout, cancelled or length"`
+	Cached          bool   `json:"cached,omitempty" jsonschema:"true when the response was served from the response cache"`
+	TrimmedMessages int    `json:"trimmed_messages,omitempty" jsonschema:"number of older session messages left out to fit the context window"`
 }
 
 // Note: Code is deprecated. Use HandlerFactory.CodeHandler() instead.
@@ -0,0 +1,34 @@
+package core
+
+// maxCompareModels bounds the number of models of a single compare-models call
+const maxCompareModels = 10
+
+// CompareModelsInput represents the input for the compare-models tool
+type CompareModelsInput struct {
+	Models       []string       `json:"models" jsonschema:"the Ollama models to compare"`
+	Message      string         `json:"message" jsonschema:"the message sent to every model"`
+	SystemPrompt string         `json:"system_prompt,omitempty" jsonschema:"system prompt sent to every model (optional)"`
+	Options      map[string]any `json:"options,omitempty" jsonschema:"model options sent to every model (optional)"`
+	Temperature  *float32       `json:"temperature,omitempty" jsonschema:"controls randomness (0.0 to 1.0, optional)"`
+	ContextSize  *int           `json:"context_size,omitempty" jsonschema:"maximum context size in tokens (optional)"`
+	Parallel     bool           `json:"parallel,omitempty" jsonschema:"query the models at the same time instead of one after the other; needs enough VRAM to load them together (optional)"`
+	KeepAlive    *string        `json:"keep_alive,omitempty" jsonschema:"duration to keep each model loaded; sequential runs unload each model before the next one by default (optional)"`
+}
+
+// ModelAnswer is the answer of one model in a comparison
+type ModelAnswer struct {
+	Model           string `json:"model" jsonschema:"the model"`
+	Response        string `json:"response,omitempty" jsonschema:"the response from the model"`
+	Thinking        string `json:"thinking,omitempty" jsonschema:"the reasoning of the model, separate from the response"`
+	LatencyMs       int64  `json:"latency_ms,omitempty" jsonschema:"wall-clock time of the request in milliseconds, including model load; left out for cached answers"`
+	Usage           *Usage `json:"usage,omitempty" jsonschema:"token counts, speed and timings of the generation"`
+	Truncated       bool   `json:"truncated,omitempty" jsonschema:"true when the response is incomplete"`
+	TruncatedReason string `json:"truncated_reason,omitempty" jsonschema:"why the response is incomplete: timeout, cancelled or length"`
+	Cached          bool   `json:"cached,omitempty" jsonschema:"true when the answer was served from the response cache; its usage is the one of the original generation"`
+	Error           string `json:"error,omitempty" jsonschema:"why the model failed to answer"`
+}
+
+// CompareModelsOutput represents the output from the compare-models tool
+type CompareModelsOutput struct {
+	Answers []ModelAnswer `json:"answers" jsonschema:"one answer per model, in the order of the models input"`
+}
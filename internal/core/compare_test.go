@@ -0,0 +1,114 @@
+package core_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Compare models", func() {
+	var (
+		ollama  *fakeOllama
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.firstDelay = 20 * time.Millisecond
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{{
+				Message: api.Message{Role: "assistant", Content: "answer from " + req.Model},
+				Done:    true,
+				Metrics: api.Metrics{EvalCount: 4, EvalDuration: time.Second},
+			}}
+		}
+		factory = core.NewHandlerFactory(core.NewServer(&core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32000,
+			ChatModel:   "test-chat-model",
+			KeepAlive:   "1m",
+		}))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should query the models one after the other and unload each of them", func() {
+		_, output, err := factory.CompareModelsHandler()(context.Background(), nil, core.CompareModelsInput{
+			Models:       []string{"qwen3-coder:30b", "codellama:13b"},
+			Message:      "write fizzbuzz",
+			SystemPrompt: "Answer with code only",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Answers).To(HaveLen(2))
+		Expect(output.Answers[0].Model).To(Equal("qwen3-coder:30b"))
+		Expect(output.Answers[0].Response).To(Equal("answer from qwen3-coder:30b"))
+		Expect(output.Answers[0].LatencyMs).To(BeNumerically(">=", 20))
+		Expect(output.Answers[0].Usage.TokensPerSecond).To(BeNumerically("==", 4))
+		Expect(output.Answers[1].Response).To(Equal("answer from codellama:13b"))
+		Expect(ollama.peakChats.Load()).To(BeEquivalentTo(1))
+
+		for _, request := range ollama.ChatRequests() {
+			Expect(request.Messages[0].Content).To(Equal("Answer with code only"))
+			Expect(request.KeepAlive).To(Equal(&api.Duration{Duration: 0}))
+		}
+	})
+
+	It("should query the models at the same time when asked", func() {
+		_, output, err := factory.CompareModelsHandler()(context.Background(), nil, core.CompareModelsInput{
+			Models:   []string{"a", "b", "c"},
+			Message:  "hi",
+			Parallel: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Answers).To(HaveLen(3))
+		Expect(output.Answers[2].Response).To(Equal("answer from c"))
+		Expect(ollama.peakChats.Load()).To(BeEquivalentTo(3))
+		Expect(ollama.ChatRequests()[0].KeepAlive).To(Equal(&api.Duration{Duration: time.Minute}))
+	})
+
+	It("should bound the parallel requests by the batch concurrency", func() {
+		factory.GetServer().GetConfig().BatchConcurrency = 2
+
+		_, output, err := factory.CompareModelsHandler()(context.Background(), nil, core.CompareModelsInput{
+			Models:   []string{"a", "b", "c", "d"},
+			Message:  "hi",
+			Parallel: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Answers).To(HaveLen(4))
+		Expect(output.Answers[3].Response).To(Equal("answer from d"))
+		Expect(ollama.peakChats.Load()).To(BeEquivalentTo(2))
+	})
+
+	It("should report a failing model next to the other answers", func() {
+		answer := ollama.chat
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			if req.Model == "missing" {
+				return nil
+			}
+			return answer(req)
+		}
+
+		_, output, err := factory.CompareModelsHandler()(context.Background(), nil, core.CompareModelsInput{
+			Models:  []string{"missing", "b"},
+			Message: "hi",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Answers[0].Error).To(ContainSubstring("not found"))
+		Expect(output.Answers[1].Error).To(BeEmpty())
+		Expect(output.Answers[1].Response).To(Equal("answer from b"))
+	})
+
+	It("should reject duplicate models", func() {
+		_, _, err := factory.CompareModelsHandler()(context.Background(), nil, core.CompareModelsInput{Models: []string{"a", "a"}, Message: "hi"})
+		Expect(err).To(MatchError(ContainSubstring("listed twice")))
+	})
+})
@@ -0,0 +1,24 @@
+package core
+
+// CompleteInput represents the input for fill-in-the-middle code completion
+type CompleteInput struct {
+	Model       string         `json:"model,omitempty" jsonschema:"the Ollama model to use, defaults to the code model (optional)"`
+	Prompt      string         `json:"prompt" jsonschema:"the code before the cursor"`
+	Suffix      string         `json:"suffix,omitempty" jsonschema:"the code after the cursor (optional)"`
+	Raw         bool           `json:"raw,omitempty" jsonschema:"send the prompt verbatim, without the model's template; the prompt must then contain the FIM tokens itself and suffix must be empty (optional)"`
+	Stop        []string       `json:"stop,omitempty" jsonschema:"sequences that end the completion (optional)"`
+	MaxTokens   *int           `json:"max_tokens,omitempty" jsonschema:"maximum number of tokens to generate (optional)"`
+	Temperature *float32       `json:"temperature,omitempty" jsonschema:"controls randomness (0.0 to 1.0, optional)"`
+	ContextSize *int           `json:"context_size,omitempty" jsonschema:"maximum context size in tokens (optional)"`
+	Options     map[string]any `json:"options,omitempty" jsonschema:"additional Ollama model options, such as seed or repeat_penalty (optional)"`
+	KeepAlive   *string        `json:"keep_alive,omitempty" jsonschema:"duration to keep the model loaded in memory (optional)"`
+}
+
+// CompleteOutput represents the output from the complete tool
+type CompleteOutput struct {
+	Model           string `json:"model,omitempty" jsonschema:"the model that answered"`
+	Completion      string `json:"completion" jsonschema:"the code to insert between the prompt and the suffix"`
+	Usage           *Usage `json:"usage,omitempty" jsonschema:"token counts, speed and timings of the generation"`
+	Truncated       bool   `json:"truncated,omitempty" jsonschema:"true when the completion is incomplete"`
+	TruncatedReason string `json:"truncated_reason,omitempty" jsonschema:"why the completion is incomplete: timeout, cancelled or length"`
+}
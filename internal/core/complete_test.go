@@ -0,0 +1,102 @@
+package core_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Complete", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.generate = func(req api.GenerateRequest) []api.GenerateResponse {
+			return []api.GenerateResponse{
+				{Response: "a + "},
+				{Response: "b"},
+				{Done: true, DoneReason: "stop", Metrics: api.Metrics{EvalCount: 3}},
+			}
+		}
+		config = &core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32000,
+			ChatModel:   "test-chat-model",
+			CodeModel:   "test-code-model",
+			KeepAlive:   "1m",
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should return only the code inserted between the prefix and the suffix", func() {
+		_, output, err := factory.CompleteHandler()(context.Background(), nil, core.CompleteInput{
+			Prompt: "func add(a, b int) int {\n\treturn ",
+			Suffix: "\n}\n",
+			Stop:   []string{"\n\n"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Completion).To(Equal("a + b"))
+		Expect(output.Truncated).To(BeFalse())
+		Expect(output.Usage.CompletionTokens).To(Equal(3))
+
+		requests := ollama.GenerateRequests()
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Model).To(Equal("test-code-model"))
+		Expect(requests[0].Prompt).To(Equal("func add(a, b int) int {\n\treturn "))
+		Expect(requests[0].Suffix).To(Equal("\n}\n"))
+		Expect(requests[0].Raw).To(BeFalse())
+		Expect(requests[0].Options).To(HaveKeyWithValue("stop", ConsistOf("\n\n")))
+	})
+
+	It("should send raw prompts verbatim", func() {
+		maxTokens := 64
+		_, _, err := factory.CompleteHandler()(context.Background(), nil, core.CompleteInput{
+			Model:     "other-coder",
+			Prompt:    "<|fim_prefix|>x = <|fim_suffix|>\n<|fim_middle|>",
+			Raw:       true,
+			MaxTokens: &maxTokens,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		requests := ollama.GenerateRequests()
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Model).To(Equal("other-coder"))
+		Expect(requests[0].Raw).To(BeTrue())
+		Expect(requests[0].Options).To(HaveKeyWithValue("num_predict", BeNumerically("==", 64)))
+	})
+
+	It("should reject a suffix in raw mode", func() {
+		_, _, err := factory.CompleteHandler()(context.Background(), nil, core.CompleteInput{Prompt: "x", Suffix: "y", Raw: true})
+		Expect(err).To(MatchError(ContainSubstring("raw mode")))
+		Expect(ollama.GenerateRequests()).To(BeEmpty())
+	})
+
+	It("should reject an empty prompt and suffix", func() {
+		_, _, err := factory.CompleteHandler()(context.Background(), nil, core.CompleteInput{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return the partial completion on timeout", func() {
+		ollama.chunkDelay = 300 * time.Millisecond
+		config.Timeouts = map[string]core.Timeouts{"complete": {Total: 150 * time.Millisecond}}
+
+		_, output, err := factory.CompleteHandler()(context.Background(), nil, core.CompleteInput{Prompt: "x = "})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Completion).To(Equal("a + "))
+		Expect(output.TruncatedReason).To(Equal("timeout"))
+	})
+})
@@ -4,7 +4,9 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ollama/ollama/api"
@@ -15,7 +17,15 @@ const (
 	DefaultContextSize = 32000
 	DefaultCodeModel   = "qwen3-coder:30b"
 	DefaultChatModel   = "gpt-oss:20b"
+	DefaultEmbedModel  = "nomic-embed-text"
 	DefaultKeepAlive   = "1m"
+
+	// DefaultFormatRetries is the number of times a reply is retried when it
+	// does not match the requested format
+	DefaultFormatRetries = 2
+
+	// DefaultMaxToolIterations limits the rounds of built-in tool calls in a chat
+	DefaultMaxToolIterations = 8
 )
 
 // Config holds the configuration for the Ollama MCP server
@@ -24,7 +34,77 @@ type Config struct {
 	ContextSize int
 	CodeModel   string
 	ChatModel   string
+	EmbedModel  string
 	KeepAlive   string
+
+	// FormatRetries is the number of extra attempts made when a structured reply is invalid
+	FormatRetries int
+
+	// MaxToolIterations limits the rounds of built-in tool calls executed for a single chat
+	MaxToolIterations int
+
+	// AllowedRoots are the directories the server may read local files from
+	AllowedRoots []string
+
+	// MaxAttachmentBytes limits the size of the files attached to a single chat request
+	MaxAttachmentBytes int
+
+	// Timeouts overrides the default timeouts per tool name, "*" applying to all tools
+	Timeouts map[string]Timeouts
+
+	// MaxSessions bounds the number of conversation sessions kept in memory
+	MaxSessions int
+
+	// SessionTTL is how long an idle conversation session is kept
+	SessionTTL time.Duration
+
+	// IndexDir is the directory holding the document indexes
+	IndexDir string
+
+	// BatchConcurrency is the maximum number of chat-batch items, or of compare-models
+	// models run in parallel, sent to Ollama at the same time
+	BatchConcurrency int
+
+	// PromptsDir is an optional directory of prompt files added to the built-in prompts
+	PromptsDir string
+
+	// CacheEnabled turns on the cache of deterministic chat responses
+	CacheEnabled bool
+
+	// CacheDir is the directory backing the response cache, empty to keep it in memory only
+	CacheDir string
+
+	// CacheTTL is how long a cached response is reused
+	CacheTTL time.Duration
+
+	// CacheMaxEntries bounds the number of cached responses
+	CacheMaxEntries int
+
+	// Retry controls how requests failing with transient Ollama errors are retried
+	Retry RetryPolicy
+
+	// FallbackModels lists per tool name the models tried in order when the default
+	// model cannot answer, "*" applying to all tools
+	FallbackModels map[string][]string
+
+	// ModelContextSizes overrides the default context size of specific models. Other models
+	// default to their maximum context length, capped by ContextSize.
+	ModelContextSizes map[string]int
+
+	// ModelKeepAlive overrides KeepAlive for specific models, -1 keeping a model loaded
+	// indefinitely and 0 unloading it right after each request
+	ModelKeepAlive map[string]time.Duration
+
+	// ContextStrategy is applied when a prompt does not fit in the context size:
+	// raise, reject, trim or none
+	ContextStrategy string
+
+	// AutoPull pulls missing models before answering chat and code calls
+	AutoPull bool
+
+	// AutoPullAllowlist holds the model names or patterns that may be pulled automatically,
+	// the configured models when empty
+	AutoPullAllowlist []string
 }
 
 // LoadConfig creates a new configuration from environment variables
@@ -51,13 +131,55 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
-	return &Config{
-		Client:      client,
-		ContextSize: contextSize,
-		CodeModel:   getEnvOrDefault("OLLAMA_CODE_MODEL", DefaultCodeModel),
-		ChatModel:   getEnvOrDefault("OLLAMA_CHAT_MODEL", DefaultChatModel),
-		KeepAlive:   getEnvOrDefault("OLLAMA_KEEP_ALIVE", DefaultKeepAlive),
-	}, nil
+	config := &Config{
+		Client:        client,
+		ContextSize:   contextSize,
+		CodeModel:     getEnvOrDefault("OLLAMA_CODE_MODEL", DefaultCodeModel),
+		ChatModel:     getEnvOrDefault("OLLAMA_CHAT_MODEL", DefaultChatModel),
+		EmbedModel:    getEnvOrDefault("OLLAMA_EMBED_MODEL", DefaultEmbedModel),
+		KeepAlive:     getEnvOrDefault("OLLAMA_KEEP_ALIVE", DefaultKeepAlive),
+		FormatRetries: GetEnvIntOrDefault("OLLAMA_FORMAT_RETRIES", DefaultFormatRetries),
+
+		MaxToolIterations: GetEnvIntOrDefault("OLLAMA_MAX_TOOL_ITERATIONS", DefaultMaxToolIterations),
+		AllowedRoots:      ParsePathList(os.Getenv("OLLAMA_ALLOWED_ROOTS")),
+		IndexDir:          getEnvOrDefault("OLLAMA_INDEX_DIR", DefaultIndexDir()),
+		BatchConcurrency:  GetEnvIntOrDefault("OLLAMA_BATCH_CONCURRENCY", DefaultBatchConcurrency),
+		PromptsDir:        os.Getenv("OLLAMA_PROMPTS_DIR"),
+		CacheEnabled:      GetEnvBoolOrDefault("OLLAMA_CACHE", false),
+		CacheDir:          getEnvOrDefault("OLLAMA_CACHE_DIR", DefaultCacheDir()),
+		CacheTTL:          GetEnvDurationOrDefault("OLLAMA_CACHE_TTL", DefaultCacheTTL),
+		CacheMaxEntries:   GetEnvIntOrDefault("OLLAMA_CACHE_MAX_ENTRIES", DefaultCacheMaxEntries),
+		ContextStrategy:   getEnvOrDefault("OLLAMA_CONTEXT_STRATEGY", DefaultContextStrategy),
+		AutoPull:          GetEnvBoolOrDefault("OLLAMA_AUTO_PULL", false),
+		AutoPullAllowlist: ParseModelList(os.Getenv("OLLAMA_AUTO_PULL_ALLOWLIST")),
+		Retry: RetryPolicy{
+			MaxAttempts:    GetEnvIntOrDefault("OLLAMA_RETRY_ATTEMPTS", DefaultRetryAttempts),
+			InitialBackoff: GetEnvDurationOrDefault("OLLAMA_RETRY_BACKOFF", DefaultRetryBackoff),
+			MaxBackoff:     GetEnvDurationOrDefault("OLLAMA_RETRY_MAX_BACKOFF", DefaultRetryMaxBackoff),
+		},
+		MaxAttachmentBytes: GetEnvIntOrDefault("OLLAMA_MAX_ATTACHMENT_BYTES", DefaultMaxAttachmentBytes),
+		MaxSessions:        GetEnvIntOrDefault("OLLAMA_MAX_SESSIONS", DefaultMaxSessions),
+		SessionTTL:         GetEnvDurationOrDefault("OLLAMA_SESSION_TTL", DefaultSessionTTL),
+	}
+
+	// Invalid timeouts fall back to the defaults, like an invalid context size
+	if err := config.SetTimeouts(os.Getenv("OLLAMA_TIMEOUT"), os.Getenv("OLLAMA_FIRST_TOKEN_TIMEOUT"), os.Getenv("OLLAMA_IDLE_TIMEOUT")); err != nil {
+		config.Timeouts = nil
+	}
+	if fallbacks, err := ParseFallbackModels(os.Getenv("OLLAMA_FALLBACK_MODELS")); err == nil {
+		config.FallbackModels = fallbacks
+	}
+	if sizes, err := ParseModelContextSizes(os.Getenv("OLLAMA_MODEL_CONTEXT_SIZES")); err == nil {
+		config.ModelContextSizes = sizes
+	}
+	if _, err := ParseKeepAlive(config.KeepAlive); err != nil {
+		config.KeepAlive = DefaultKeepAlive
+	}
+	if policies, err := ParseModelKeepAlive(os.Getenv("OLLAMA_MODEL_KEEP_ALIVE")); err == nil {
+		config.ModelKeepAlive = policies
+	}
+
+	return config, nil
 }
 
 // LoadConfigFromFlags creates a new configuration from command-line flags
@@ -95,21 +217,38 @@ func LoadConfigFromFlags(host string, contextSize int, codeModel, chatModel, kee
 	}
 
 	return &Config{
-		Client:      client,
-		ContextSize: contextSize,
-		CodeModel:   codeModel,
-		ChatModel:   chatModel,
-		KeepAlive:   keepAlive,
+		Client:        client,
+		ContextSize:   contextSize,
+		CodeModel:     codeModel,
+		ChatModel:     chatModel,
+		EmbedModel:    DefaultEmbedModel,
+		KeepAlive:     keepAlive,
+		FormatRetries: DefaultFormatRetries,
+
+		MaxToolIterations: DefaultMaxToolIterations,
+		IndexDir:          DefaultIndexDir(),
+		BatchConcurrency:  DefaultBatchConcurrency,
+		CacheDir:          DefaultCacheDir(),
+		CacheTTL:          DefaultCacheTTL,
+		CacheMaxEntries:   DefaultCacheMaxEntries,
+		ContextStrategy:   DefaultContextStrategy,
+		Retry:             DefaultRetryPolicy(),
+
+		MaxAttachmentBytes: DefaultMaxAttachmentBytes,
+		MaxSessions:        DefaultMaxSessions,
+		SessionTTL:         DefaultSessionTTL,
 	}, nil
 }
 
 // GetModel returns the model for the specified tool
 func (c *Config) GetModel(toolName string) string {
 	switch toolName {
-	case "code":
+	case "code", "complete":
 		return c.CodeModel
 	case "chat":
 		return c.ChatModel
+	case "embed":
+		return c.EmbedModel
 	default:
 		return c.ChatModel
 	}
@@ -117,13 +256,37 @@ func (c *Config) GetModel(toolName string) string {
 
 // Server holds the MCP server instance with its configuration
 type Server struct {
-	config *Config
+	config   *Config
+	sessions *SessionStore
+	indexes  *IndexStore
+	cache    *ResponseCache
+
+	// contextLengths caches the maximum context length of each model
+	contextLengths *contextLengthCache
 }
 
 // NewServer creates a new server instance with the given configuration
 func NewServer(config *Config) *Server {
+	var indexDir string
+	var cache *ResponseCache
+	var maxSessions int
+	var sessionTTL time.Duration
+	if config != nil {
+		indexDir = config.IndexDir
+		maxSessions = config.MaxSessions
+		sessionTTL = config.SessionTTL
+		if config.CacheEnabled {
+			cache = NewResponseCache(config.CacheDir, config.CacheTTL, config.CacheMaxEntries)
+		}
+	}
+
 	return &Server{
-		config: config,
+		config:   config,
+		sessions: NewSessionStore(maxSessions, sessionTTL),
+		indexes:  NewIndexStore(indexDir),
+		cache:    cache,
+
+		contextLengths: newContextLengthCache(),
 	}
 }
 
@@ -137,6 +300,21 @@ func (s *Server) GetClient() *api.Client {
 	return s.config.Client
 }
 
+// GetSessions returns the conversation session store
+func (s *Server) GetSessions() *SessionStore {
+	return s.sessions
+}
+
+// GetIndexes returns the document index store
+func (s *Server) GetIndexes() *IndexStore {
+	return s.indexes
+}
+
+// GetCache returns the response cache, or nil when caching is disabled
+func (s *Server) GetCache() *ResponseCache {
+	return s.cache
+}
+
 // GetDefaultModel returns the default model for a tool
 func (s *Server) GetDefaultModel(toolName string) string {
 	return s.config.GetModel(toolName)
@@ -160,6 +338,49 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// GetEnvIntOrDefault returns the non-negative integer value of an environment variable or the default
+func GetEnvIntOrDefault(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvBoolOrDefault returns the boolean value of an environment variable, as parsed by
+// strconv.ParseBool, or the default
+func GetEnvBoolOrDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvDurationOrDefault returns the positive duration value of an environment variable or the default
+func GetEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// ParsePathList splits a list of paths separated by the OS path list separator,
+// dropping empty entries
+func ParsePathList(value string) []string {
+	var paths []string
+	for _, path := range filepath.SplitList(value) {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
 // createHTTPClient creates an HTTP client with custom settings
 func createHTTPClient() *http.Client {
 	transport := &http.Transport{
@@ -171,8 +392,8 @@ func createHTTPClient() *http.Client {
 		ResponseHeaderTimeout: 5 * time.Minute,
 	}
 
+	// Request deadlines are set per tool through the request context
 	return &http.Client{
 		Transport: transport,
-		Timeout:   10 * time.Minute,
 	}
 }
@@ -53,6 +53,7 @@ var _ = Describe("Config", func() {
 				Expect(config.ContextSize).To(Equal(core.DefaultContextSize))
 				Expect(config.CodeModel).To(Equal(core.DefaultCodeModel))
 				Expect(config.ChatModel).To(Equal(core.DefaultChatModel))
+				Expect(config.EmbedModel).To(Equal(core.DefaultEmbedModel))
 				Expect(config.KeepAlive).To(Equal(core.DefaultKeepAlive))
 				Expect(config.Client).NotTo(BeNil())
 			})
@@ -101,13 +102,27 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	DescribeTable("GetEnvBoolOrDefault",
+		func(value string, expected bool) {
+			GinkgoT().Setenv("OLLAMA_TEST_BOOL", value)
+			Expect(core.GetEnvBoolOrDefault("OLLAMA_TEST_BOOL", false)).To(Equal(expected))
+		},
+		Entry("true", "true", true),
+		Entry("one", "1", true),
+		Entry("upper case", "TRUE", true),
+		Entry("false", "false", false),
+		Entry("invalid", "yes", false),
+		Entry("empty", "", false),
+	)
+
 	Describe("Config.GetModel", func() {
 		var config *core.Config
 
 		BeforeEach(func() {
 			config = &core.Config{
-				CodeModel: "test-code-model",
-				ChatModel: "test-chat-model",
+				CodeModel:  "test-code-model",
+				ChatModel:  "test-chat-model",
+				EmbedModel: "test-embed-model",
 			}
 		})
 
@@ -118,6 +133,8 @@ var _ = Describe("Config", func() {
 			},
 			Entry("code tool", "code", "test-code-model"),
 			Entry("chat tool", "chat", "test-chat-model"),
+			Entry("complete tool", "complete", "test-code-model"),
+			Entry("embed tool", "embed", "test-embed-model"),
 			Entry("unknown tool", "unknown", "test-chat-model"),
 			Entry("empty tool name", "", "test-chat-model"),
 		)
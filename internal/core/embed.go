@@ -0,0 +1,41 @@
+package core
+
+import (
+	"math"
+)
+
+// EmbedInput represents the input for the embed tool
+type EmbedInput struct {
+	Model      string   `json:"model,omitempty" jsonschema:"the Ollama embedding model to use, defaults to the embed model (optional)"`
+	Input      []string `json:"input" jsonschema:"the texts to embed"`
+	Query      string   `json:"query,omitempty" jsonschema:"text to compare every input against with cosine similarity (optional)"`
+	Truncate   *bool    `json:"truncate,omitempty" jsonschema:"truncate inputs that exceed the model's context instead of failing, true by default (optional)"`
+	Dimensions int      `json:"dimensions,omitempty" jsonschema:"number of dimensions to keep in each vector, for models that support it (optional)"`
+}
+
+// EmbedOutput represents the output from the embed tool
+type EmbedOutput struct {
+	Model        string      `json:"model" jsonschema:"the model that computed the embeddings"`
+	Embeddings   [][]float32 `json:"embeddings" jsonschema:"one vector per input, in input order"`
+	Scores       []float64   `json:"scores,omitempty" jsonschema:"cosine similarity of each input to the query, in input order"`
+	PromptTokens int         `json:"prompt_tokens,omitempty" jsonschema:"number of tokens embedded"`
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors,
+// or 0 when either vector is empty or they differ in length
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
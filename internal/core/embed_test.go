@@ -0,0 +1,86 @@
+package core_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Embed", func() {
+	var (
+		ollama  *fakeOllama
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.embed = func(text string) []float32 {
+			switch text {
+			case "cat", "kitten":
+				return []float32{1, 0}
+			case "feline":
+				return []float32{1, 1}
+			default:
+				return []float32{0, 1}
+			}
+		}
+		factory = core.NewHandlerFactory(core.NewServer(&core.Config{
+			Client:     ollama.Client(),
+			EmbedModel: "test-embed-model",
+		}))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should return one vector per input with the default model", func() {
+		_, output, err := factory.EmbedHandler()(context.Background(), nil, core.EmbedInput{Input: []string{"cat", "car"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Model).To(Equal("test-embed-model"))
+		Expect(output.Embeddings).To(Equal([][]float32{{1, 0}, {0, 1}}))
+		Expect(output.Scores).To(BeNil())
+	})
+
+	It("should score every input against the query", func() {
+		_, output, err := factory.EmbedHandler()(context.Background(), nil, core.EmbedInput{
+			Input: []string{"kitten", "car", "feline"},
+			Query: "cat",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Embeddings).To(HaveLen(3))
+		Expect(output.Scores).To(HaveLen(3))
+		Expect(output.Scores[0]).To(BeNumerically("~", 1, 1e-9))
+		Expect(output.Scores[1]).To(BeNumerically("~", 0, 1e-9))
+		Expect(output.Scores[2]).To(BeNumerically("~", 0.7071, 1e-4))
+
+		requests := ollama.EmbedRequests()
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Input).To(Equal([]any{"kitten", "car", "feline", "cat"}))
+	})
+
+	It("should forward truncate and dimensions", func() {
+		truncate := false
+		_, _, err := factory.EmbedHandler()(context.Background(), nil, core.EmbedInput{
+			Model:      "other-embed-model",
+			Input:      []string{"cat"},
+			Truncate:   &truncate,
+			Dimensions: 256,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		requests := ollama.EmbedRequests()
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Model).To(Equal("other-embed-model"))
+		Expect(requests[0].Truncate).To(Equal(&truncate))
+		Expect(requests[0].Dimensions).To(Equal(256))
+	})
+
+	It("should reject an empty batch", func() {
+		_, _, err := factory.EmbedHandler()(context.Background(), nil, core.EmbedInput{})
+		Expect(err).To(MatchError(ContainSubstring("input cannot be empty")))
+	})
+})
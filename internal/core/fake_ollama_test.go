@@ -0,0 +1,353 @@
+package core_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// fakeOllama is a minimal Ollama API server used to exercise the handlers
+type fakeOllama struct {
+	server *httptest.Server
+
+	mu               sync.Mutex
+	chatRequests     []api.ChatRequest
+	generateRequests []api.GenerateRequest
+	embedRequests    []api.EmbedRequest
+	pullRequests     []api.PullRequest
+
+	// aborted counts the streams interrupted by the client
+	aborted atomic.Int32
+
+	// shows counts the show requests
+	shows atomic.Int32
+
+	// inFlight and peakChats track how many chat requests are served at the same time
+	inFlight  atomic.Int32
+	peakChats atomic.Int32
+
+	// chat returns the stream of responses for a chat request, or nil for a missing model
+	chat func(req api.ChatRequest) []api.ChatResponse
+
+	// generate returns the stream of responses for a generate request
+	generate func(req api.GenerateRequest) []api.GenerateResponse
+
+	// embed returns the vector of a single text
+	embed func(text string) []float32
+
+	// firstDelay and chunkDelay slow the chat stream down before the first and next chunks
+	firstDelay time.Duration
+	chunkDelay time.Duration
+
+	// models holds the show responses of the installed models
+	models map[string]*api.ShowResponse
+
+	// loadErrors holds the error returned when a model fails to load, per model
+	loadErrors map[string]string
+
+	// slowModels holds the delay before the first chunk of a model, overriding firstDelay
+	slowModels map[string]time.Duration
+
+	// failures holds, per path, the status codes of the next failed requests,
+	// 0 dropping the connection
+	failures map[string][]int
+}
+
+// newFakeOllama starts a fake Ollama server replying "ok" to every chat
+func newFakeOllama() *fakeOllama {
+	f := &fakeOllama{
+		chat: func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{{Message: api.Message{Role: "assistant", Content: "ok"}, Done: true}}
+		},
+		generate: func(req api.GenerateRequest) []api.GenerateResponse {
+			return []api.GenerateResponse{{Response: "ok", Done: true}}
+		},
+		embed: func(text string) []float32 {
+			return []float32{float32(len(text)), 1}
+		},
+		models:     make(map[string]*api.ShowResponse),
+		loadErrors: make(map[string]string),
+		slowModels: make(map[string]time.Duration),
+		failures:   make(map[string][]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/chat", f.handleChat)
+	mux.HandleFunc("/api/generate", f.handleGenerate)
+	mux.HandleFunc("/api/embed", f.handleEmbed)
+	mux.HandleFunc("/api/show", f.handleShow)
+	mux.HandleFunc("/api/tags", f.handleTags)
+	mux.HandleFunc("/api/pull", f.handlePull)
+	f.server = httptest.NewServer(f.injectFailures(mux))
+	return f
+}
+
+// failNext makes the next requests to a path fail with the given status codes, in order
+func (f *fakeOllama) failNext(path string, statuses ...int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[path] = append(f.failures[path], statuses...)
+}
+
+// injectFailures fails the requests registered with failNext before they reach the handler
+func (f *fakeOllama) injectFailures(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		statuses := f.failures[r.URL.Path]
+		failed := len(statuses) > 0
+		var status int
+		if failed {
+			status, f.failures[r.URL.Path] = statuses[0], statuses[1:]
+		}
+		f.mu.Unlock()
+
+		switch {
+		case !failed:
+			next.ServeHTTP(w, r)
+		case status == 0:
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+		default:
+			writeError(w, status, http.StatusText(status))
+		}
+	})
+}
+
+// Client returns an Ollama client connected to the fake server
+func (f *fakeOllama) Client() *api.Client {
+	baseURL, _ := url.Parse(f.server.URL)
+	return api.NewClient(baseURL, f.server.Client())
+}
+
+// Close shuts the fake server down
+func (f *fakeOllama) Close() {
+	f.server.Close()
+}
+
+// ChatRequests returns the chat requests received so far
+func (f *fakeOllama) ChatRequests() []api.ChatRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]api.ChatRequest(nil), f.chatRequests...)
+}
+
+// GenerateRequests returns the generate requests received so far
+func (f *fakeOllama) GenerateRequests() []api.GenerateRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]api.GenerateRequest(nil), f.generateRequests...)
+}
+
+// EmbedRequests returns the embed requests received so far
+func (f *fakeOllama) EmbedRequests() []api.EmbedRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]api.EmbedRequest(nil), f.embedRequests...)
+}
+
+// PullRequests returns the pull requests received so far
+func (f *fakeOllama) PullRequests() []api.PullRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]api.PullRequest(nil), f.pullRequests...)
+}
+
+// installed reports whether a model is installed
+func (f *fakeOllama) installed(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.models[name]
+	return ok
+}
+
+func (f *fakeOllama) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req api.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	current := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		peak := f.peakChats.Load()
+		if current <= peak || f.peakChats.CompareAndSwap(peak, current) {
+			break
+		}
+	}
+
+	f.mu.Lock()
+	f.chatRequests = append(f.chatRequests, req)
+	chat, firstDelay, chunkDelay := f.chat, f.firstDelay, f.chunkDelay
+	loadError := f.loadErrors[req.Model]
+	if delay, ok := f.slowModels[req.Model]; ok {
+		firstDelay = delay
+	}
+	f.mu.Unlock()
+
+	if loadError != "" {
+		writeError(w, http.StatusInternalServerError, loadError)
+		return
+	}
+	responses := chat(req)
+	if responses == nil {
+		writeError(w, http.StatusNotFound, "model '"+req.Model+"' not found")
+		return
+	}
+	streamResponses(f, w, r, responses, firstDelay, chunkDelay)
+}
+
+func (f *fakeOllama) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	var req api.GenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.generateRequests = append(f.generateRequests, req)
+	generate, firstDelay, chunkDelay := f.generate, f.firstDelay, f.chunkDelay
+	loadError := f.loadErrors[req.Model]
+	f.mu.Unlock()
+
+	if loadError != "" {
+		writeError(w, http.StatusInternalServerError, loadError)
+		return
+	}
+	streamResponses(f, w, r, generate(req), firstDelay, chunkDelay)
+}
+
+// streamResponses writes the responses as newline-delimited JSON, pausing before each chunk
+func streamResponses[T any](f *fakeOllama, w http.ResponseWriter, r *http.Request, responses []T, firstDelay, chunkDelay time.Duration) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+
+	encoder := json.NewEncoder(w)
+	for i, response := range responses {
+		delay := chunkDelay
+		if i == 0 {
+			delay = firstDelay
+		}
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			f.aborted.Add(1)
+			return
+		}
+
+		if err := encoder.Encode(response); err != nil {
+			return
+		}
+		w.(http.Flusher).Flush()
+	}
+}
+
+func (f *fakeOllama) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	var req api.EmbedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.embedRequests = append(f.embedRequests, req)
+	embed := f.embed
+	f.mu.Unlock()
+
+	var texts []string
+	switch input := req.Input.(type) {
+	case string:
+		texts = []string{input}
+	case []any:
+		for _, text := range input {
+			texts = append(texts, text.(string))
+		}
+	}
+
+	response := api.EmbedResponse{Model: req.Model, PromptEvalCount: len(texts)}
+	for _, text := range texts {
+		response.Embeddings = append(response.Embeddings, embed(text))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeOllama) handleShow(w http.ResponseWriter, r *http.Request) {
+	var req api.ShowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Older clients send the model in the deprecated name field
+	name := req.Model
+	if name == "" {
+		name = req.Name
+	}
+
+	f.shows.Add(1)
+	f.mu.Lock()
+	response, ok := f.models[name]
+	f.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "model '"+name+"' not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeOllama) handleTags(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	response := api.ListResponse{Models: []api.ListModelResponse{}}
+	for name := range f.models {
+		response.Models = append(response.Models, api.ListModelResponse{Name: name, Model: name, Digest: "digest-" + name})
+	}
+	f.mu.Unlock()
+
+	sort.Slice(response.Models, func(i, j int) bool { return response.Models[i].Name < response.Models[j].Name })
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// handlePull installs the requested model after streaming the download progress
+func (f *fakeOllama) handlePull(w http.ResponseWriter, r *http.Request) {
+	var req api.PullRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.pullRequests = append(f.pullRequests, req)
+	f.models[req.Model] = &api.ShowResponse{}
+	f.mu.Unlock()
+
+	streamResponses(f, w, r, []api.ProgressResponse{
+		{Status: "pulling manifest"},
+		{Status: "pulling a", Digest: "sha256:a", Total: 100, Completed: 50},
+		{Status: "pulling a", Digest: "sha256:a", Total: 100, Completed: 100},
+		{Status: "pulling b", Digest: "sha256:b", Total: 300, Completed: 0},
+		{Status: "pulling b", Digest: "sha256:b", Total: 300, Completed: 300},
+		{Status: "success"},
+	}, 0, 0)
+}
+
+// writeError replies with an Ollama error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ParseFallbackModels parses a comma-separated list of tool=model|model entries, such as
+// "code=qwen2.5-coder:7b|qwen2.5-coder:3b,chat=llama3.2". The models of a tool are tried
+// in order after its default model. An entry without a tool name is stored under "*".
+func ParseFallbackModels(spec string) (map[string][]string, error) {
+	fallbacks := make(map[string][]string)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		tool, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			tool, value = "*", entry
+		}
+		tool = strings.TrimSpace(tool)
+
+		var models []string
+		for _, model := range strings.Split(value, "|") {
+			if model = strings.TrimSpace(model); model != "" {
+				models = append(models, model)
+			}
+		}
+		if len(models) == 0 {
+			return nil, fmt.Errorf("no fallback models for %s", tool)
+		}
+		fallbacks[tool] = models
+	}
+	return fallbacks, nil
+}
+
+// GetFallbackModels returns the models tried in order when the default model of a tool
+// cannot answer. The complete tool shares the fallbacks of the code tool, like its model.
+func (c *Config) GetFallbackModels(toolName string) []string {
+	keys := []string{toolName}
+	if toolName == "complete" {
+		keys = append(keys, "code")
+	}
+	for _, key := range append(keys, "*") {
+		if models, ok := c.FallbackModels[key]; ok {
+			return models
+		}
+	}
+	return nil
+}
+
+// modelCandidates returns the models to try for a tool call: the requested model alone,
+// or the default model of the tool followed by its fallbacks
+func (c *Config) modelCandidates(toolName, requested string) []string {
+	if requested != "" {
+		return []string{requested}
+	}
+
+	candidates := []string{c.GetModel(toolName)}
+	for _, model := range c.GetFallbackModels(toolName) {
+		if !slices.Contains(candidates, model) {
+			candidates = append(candidates, model)
+		}
+	}
+	return candidates
+}
+
+// shouldFallback reports whether another model may succeed where a model failed:
+// when it is not installed, does not fit in memory, or did not answer in time
+func shouldFallback(err error) bool {
+	return errors.Is(err, errFirstTokenTimeout) || isMemoryError(err) || isModelNotFound(err)
+}
+
+// isMemoryError reports whether Ollama failed to load a model for lack of memory
+func isMemoryError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "memory") || strings.Contains(message, "cudamalloc")
+}
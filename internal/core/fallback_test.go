@@ -0,0 +1,131 @@
+package core_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Fallback models", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+	)
+
+	requestedModels := func() []string {
+		var models []string
+		for _, req := range ollama.ChatRequests() {
+			models = append(models, req.Model)
+		}
+		return models
+	}
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			if req.Model == "qwen3-coder:30b" {
+				return nil
+			}
+			return []api.ChatResponse{{Message: api.Message{Role: "assistant", Content: "answer from " + req.Model}, Done: true}}
+		}
+		config = &core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32000,
+			CodeModel:   "qwen3-coder:30b",
+			ChatModel:   "gpt-oss:20b",
+			KeepAlive:   "1m",
+			FallbackModels: map[string][]string{
+				"code": {"qwen2.5-coder:7b", "qwen2.5-coder:3b"},
+				"*":    {"llama3.2"},
+			},
+			Retry: core.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should parse fallback chains per tool", func() {
+		fallbacks, err := core.ParseFallbackModels("code=qwen2.5-coder:7b|qwen2.5-coder:3b, llama3.2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fallbacks).To(Equal(map[string][]string{
+			"code": {"qwen2.5-coder:7b", "qwen2.5-coder:3b"},
+			"*":    {"llama3.2"},
+		}))
+
+		_, err = core.ParseFallbackModels("code=")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should share the code fallbacks with the complete tool", func() {
+		Expect(config.GetFallbackModels("complete")).To(Equal([]string{"qwen2.5-coder:7b", "qwen2.5-coder:3b"}))
+		Expect(config.GetFallbackModels("chat")).To(Equal([]string{"llama3.2"}))
+	})
+
+	It("should answer with the next model when the default model is missing", func() {
+		_, output, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{Message: "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Model).To(Equal("qwen2.5-coder:7b"))
+		Expect(output.Response).To(Equal("answer from qwen2.5-coder:7b"))
+		Expect(requestedModels()).To(Equal([]string{"qwen3-coder:30b", "qwen2.5-coder:7b"}))
+	})
+
+	It("should answer with the next model when a model does not fit in memory", func() {
+		ollama.loadErrors["qwen2.5-coder:7b"] = "model requires more system memory (9.5 GiB) than is available (6.1 GiB)"
+
+		_, output, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{Message: "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Model).To(Equal("qwen2.5-coder:3b"))
+		Expect(requestedModels()).To(Equal([]string{"qwen3-coder:30b", "qwen2.5-coder:7b", "qwen2.5-coder:3b"}))
+	})
+
+	It("should answer with the next model when a model times out before answering", func() {
+		config.Timeouts = map[string]core.Timeouts{"chat": {FirstToken: 50 * time.Millisecond}}
+		ollama.slowModels["gpt-oss:20b"] = time.Second
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Model).To(Equal("llama3.2"))
+	})
+
+	It("should report the error of the last model when every model fails", func() {
+		ollama.loadErrors["qwen2.5-coder:7b"] = "model requires more system memory"
+		ollama.loadErrors["qwen2.5-coder:3b"] = "model requires more system memory"
+
+		_, _, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{Message: "hello"})
+		Expect(err).To(MatchError(ContainSubstring("memory")))
+	})
+
+	It("should not fall back from an explicitly requested model", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Model: "qwen3-coder:30b", Message: "hello"})
+		Expect(err).To(MatchError(ContainSubstring("not found")))
+		Expect(requestedModels()).To(Equal([]string{"qwen3-coder:30b"}))
+	})
+
+	It("should not fall back on other errors", func() {
+		ollama.loadErrors["qwen3-coder:30b"] = "unexpected server error"
+		config.Retry.MaxAttempts = 1
+
+		_, _, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{Message: "hello"})
+		Expect(err).To(MatchError(ContainSubstring("unexpected server error")))
+		Expect(requestedModels()).To(Equal([]string{"qwen3-coder:30b"}))
+	})
+
+	It("should fall back for completions", func() {
+		ollama.loadErrors["qwen3-coder:30b"] = "cudaMalloc failed: out of memory"
+
+		_, output, err := factory.CompleteHandler()(context.Background(), nil, core.CompleteInput{Prompt: "func add("})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Model).To(Equal("qwen2.5-coder:7b"))
+		Expect(ollama.GenerateRequests()).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// responseFormat describes the structured output requested by the caller
+type responseFormat struct {
+	// raw is the value sent to Ollama in ChatRequest.Format
+	raw json.RawMessage
+	// schema validates the model's reply; nil when only valid JSON is required
+	schema *jsonschema.Resolved
+}
+
+// parseFormat converts the format input, either "json" or a JSON Schema object,
+// into the request format and the schema used to validate the reply
+func parseFormat(format any) (*responseFormat, error) {
+	switch f := format.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if f != "json" {
+			return nil, fmt.Errorf("format must be \"json\" or a JSON Schema object, got %q", f)
+		}
+		return &responseFormat{raw: json.RawMessage(`"json"`)}, nil
+	case map[string]any:
+		raw, err := json.Marshal(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format schema: %w", err)
+		}
+
+		var schema jsonschema.Schema
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			return nil, fmt.Errorf("invalid format schema: %w", err)
+		}
+		resolved, err := schema.Resolve(nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format schema: %w", err)
+		}
+		return &responseFormat{raw: raw, schema: resolved}, nil
+	default:
+		return nil, fmt.Errorf("format must be \"json\" or a JSON Schema object")
+	}
+}
+
+// parse decodes the model's reply and validates it against the schema
+func (f *responseFormat) parse(content string) (any, error) {
+	var value any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &value); err != nil {
+		return nil, fmt.Errorf("reply is not valid JSON: %w", err)
+	}
+
+	if f.schema != nil {
+		if err := f.schema.Validate(value); err != nil {
+			return nil, fmt.Errorf("reply does not match the schema: %w", err)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,92 @@
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Structured output", func() {
+	var (
+		ollama  *fakeOllama
+		factory *core.HandlerFactory
+		replies []string
+		schema  map[string]any
+	)
+
+	BeforeEach(func() {
+		replies = nil
+		ollama = newFakeOllama()
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			reply := replies[0]
+			if len(replies) > 1 {
+				replies = replies[1:]
+			}
+			return []api.ChatResponse{{Message: api.Message{Role: "assistant", Content: reply}, Done: true}}
+		}
+		factory = core.NewHandlerFactory(core.NewServer(&core.Config{
+			Client:        ollama.Client(),
+			ContextSize:   32000,
+			ChatModel:     "test-chat-model",
+			KeepAlive:     "1m",
+			FormatRetries: 1,
+		}))
+		schema = map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"answer": map[string]any{"type": "integer"}},
+			"required":   []any{"answer"},
+		}
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should pass the schema to Ollama and return the parsed reply", func() {
+		replies = []string{`{"answer": 42}`}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hi", Format: schema})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Structured).To(HaveKeyWithValue("answer", BeNumerically("==", 42)))
+
+		var sent map[string]any
+		Expect(json.Unmarshal(ollama.ChatRequests()[0].Format, &sent)).To(Succeed())
+		Expect(sent).To(HaveKey("properties"))
+	})
+
+	It("should retry when the reply does not match the schema", func() {
+		replies = []string{`Sure! {"answer": "many"}`, `{"answer": 7}`}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hi", Format: schema})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Response).To(Equal(`{"answer": 7}`))
+		Expect(ollama.ChatRequests()).To(HaveLen(2))
+	})
+
+	It("should fail once the retries are exhausted", func() {
+		replies = []string{`{"answer": "many"}`}
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hi", Format: schema})
+		Expect(err).To(MatchError(ContainSubstring("after 2 attempts")))
+	})
+
+	It("should accept plain JSON mode", func() {
+		replies = []string{`[1, 2]`}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hi", Format: "json"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Structured).To(HaveLen(2))
+		Expect(string(ollama.ChatRequests()[0].Format)).To(Equal(`"json"`))
+	})
+
+	It("should reject unknown formats", func() {
+		err := factory.ValidateChatInput(core.ChatInput{Message: "hi", Format: "yaml"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -2,8 +2,13 @@ package core
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -40,10 +45,6 @@ func (h *HandlerFactory) ValidateChatInput(input ChatInput) error {
 // ChatHandler returns a handler function for the chat tool
 func (h *HandlerFactory) ChatHandler() func(context.Context, *mcp.CallToolRequest, ChatInput) (*mcp.CallToolResult, ChatOutput, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, input ChatInput) (*mcp.CallToolResult, ChatOutput, error) {
-		// Add timeout to context
-		timeoutCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-		defer cancel()
-
 		// Validate input
 		if err := h.validateChatInput(input); err != nil {
 			return nil, ChatOutput{}, fmt.Errorf("invalid input: %w", err)
@@ -64,44 +65,84 @@ func (h *HandlerFactory) ChatHandler() func(context.Context, *mcp.CallToolReques
 			}
 		}
 
-		// Use default model if not specified
-		modelToUse := input.Model
-		if modelToUse == "" {
-			modelToUse = config.GetModel(toolName)
+		// Add the tool's timeout to context
+		timeouts := config.GetTimeouts(toolName)
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeouts.Total)
+		defer cancel()
+
+		// Use the default model and its fallbacks if not specified
+		candidates := config.modelCandidates(toolName, input.Model)
+
+		// Validate model names
+		for _, model := range candidates {
+			if err := h.validateModelName(model); err != nil {
+				return nil, ChatOutput{}, err
+			}
 		}
 
-		// Validate model name
-		if err := h.validateModelName(modelToUse); err != nil {
-			return nil, ChatOutput{}, err
+		// Load the conversation history when continuing a session
+		var session *Session
+		if input.SessionID != "" {
+			var err error
+			session, err = h.server.GetSessions().Get(input.SessionID)
+			if err != nil {
+				return nil, ChatOutput{}, err
+			}
 		}
 
-		// Build the chat request
-		chatRequest := &api.ChatRequest{
-			Model: modelToUse,
-			Messages: []api.Message{
-				{
-					Role:    "user",
-					Content: input.Message,
-				},
-			},
-			Stream:  new(bool), // Set to false (non-streaming)
-			Options: make(map[string]interface{}),
+		// Load the attached images
+		images, err := loadImages(config, input.Images)
+		if err != nil {
+			return nil, ChatOutput{}, fmt.Errorf("invalid input: %w", err)
+		}
+
+		// Inline the attached files before the message
+		content, err := attachFiles(timeoutCtx, req, config, input.Files, input.Message)
+		if err != nil {
+			return nil, ChatOutput{}, fmt.Errorf("invalid input: %w", err)
+		}
+
+		// Send the results of the caller's tool calls first, right after the calls
+		newMessages, err := toolResultMessages(session, input.ToolResults)
+		if err != nil {
+			return nil, ChatOutput{}, fmt.Errorf("invalid input: %w", err)
 		}
 
-		// Add system prompt if provided
-		if input.SystemPrompt != "" {
-			systemMsg := api.Message{
+		// Collect the other messages added by this exchange
+		if input.SystemPrompt != "" && (session == nil || session.lastSystemPrompt() != input.SystemPrompt) {
+			newMessages = append(newMessages, api.Message{
 				Role:    "system",
 				Content: input.SystemPrompt,
-			}
-			chatRequest.Messages = append([]api.Message{systemMsg}, chatRequest.Messages...)
+			})
+		}
+		if content != "" || len(images) > 0 {
+			newMessages = append(newMessages, api.Message{
+				Role:    "user",
+				Content: content,
+				Images:  images,
+			})
+		}
+
+		var messages []api.Message
+		if session != nil {
+			messages = append(messages, session.Messages...)
+		}
+		messages = append(messages, newMessages...)
+
+		// Always stream so that stalled generations are detected between tokens
+		stream := true
+
+		// Build the chat request
+		chatRequest := &api.ChatRequest{
+			Model:    candidates[0],
+			Messages: messages,
+			Stream:   &stream,
+			Options:  make(map[string]interface{}),
 		}
 
-		// Set context size
+		// Set context size, or resolve it per model below
 		if input.ContextSize != nil {
 			chatRequest.Options["num_ctx"] = *input.ContextSize
-		} else {
-			chatRequest.Options["num_ctx"] = config.ContextSize
 		}
 
 		// Set temperature
@@ -119,6 +160,23 @@ func (h *HandlerFactory) ChatHandler() func(context.Context, *mcp.CallToolReques
 			chatRequest.Options["top_k"] = *input.TopK
 		}
 
+		// Set the other typed sampling options
+		if input.MinP != nil {
+			chatRequest.Options["min_p"] = *input.MinP
+		}
+		if input.Seed != nil {
+			chatRequest.Options["seed"] = *input.Seed
+		}
+		if input.NumPredict != nil {
+			chatRequest.Options["num_predict"] = *input.NumPredict
+		}
+		if len(input.Stop) > 0 {
+			chatRequest.Options["stop"] = input.Stop
+		}
+		if input.RepeatPenalty != nil {
+			chatRequest.Options["repeat_penalty"] = *input.RepeatPenalty
+		}
+
 		// Add any additional options
 		if input.Options != nil {
 			for k, v := range input.Options {
@@ -126,29 +184,186 @@ func (h *HandlerFactory) ChatHandler() func(context.Context, *mcp.CallToolReques
 			}
 		}
 
-		// Set keep_alive
-		if input.KeepAlive != nil {
-			chatRequest.Options["keep_alive"] = *input.KeepAlive
-		} else {
-			chatRequest.Options["keep_alive"] = config.KeepAlive
+		// Enable or disable reasoning when requested
+		think, err := parseThink(input.Think)
+		if err != nil {
+			return nil, ChatOutput{}, fmt.Errorf("invalid input: %w", err)
+		}
+		chatRequest.Think = think
+
+		// Request structured output when a format was provided
+		format, err := parseFormat(input.Format)
+		if err != nil {
+			return nil, ChatOutput{}, fmt.Errorf("invalid input: %w", err)
+		}
+		if format != nil {
+			chatRequest.Format = format.raw
 		}
 
-		// Variable to store the final response
-		var finalResponse string
+		// Offer the caller's tools and the requested built-in tools to the model
+		tools, builtins, err := buildTools(input.Tools, input.BuiltinTools)
+		if err != nil {
+			return nil, ChatOutput{}, fmt.Errorf("invalid input: %w", err)
+		}
+		chatRequest.Tools = tools
+		call := &chatCall{req: req, config: config, timeouts: timeouts, tools: builtins, progress: newProgressCounter(req)}
 
-		// Use the official client's Chat method with timeout context
-		err := config.Client.Chat(timeoutCtx, chatRequest, func(response api.ChatResponse) error {
-			if response.Message.Content != "" {
-				finalResponse = response.Message.Content
+		// Try the fallback models in turn while a model is missing, does not fit in
+		// memory or does not start answering in time
+		cache := h.server.GetCache()
+		var cacheKeyValue string
+		var response api.ChatResponse
+		var executions []ToolExecution
+		cached := false
+		trimmed := 0
+		numCtx, explicitContext := chatRequest.Options["num_ctx"]
+		for i, model := range candidates {
+			last := i == len(candidates)-1
+			chatRequest.Model = model
+			chatRequest.Messages = messages
+			if explicitContext {
+				chatRequest.Options["num_ctx"] = numCtx
+			} else {
+				chatRequest.Options["num_ctx"] = h.defaultContextSize(timeoutCtx, config, model)
 			}
-			return nil
-		})
+			if chatRequest.KeepAlive, err = config.keepAlive(model, input.KeepAlive); err != nil {
+				return nil, ChatOutput{}, err
+			}
+
+			// Check that the model can see the attached images, pulling it first when
+			// it is missing and the auto-pull policy allows it
+			if len(images) > 0 {
+				err = requireVision(timeoutCtx, config, model)
+				if isModelNotFound(err) {
+					if err = h.autoPull(ctx, call, model, err); err == nil {
+						err = requireVision(timeoutCtx, config, model)
+					}
+				}
+				if err != nil {
+					if last {
+						return nil, ChatOutput{}, err
+					}
+					continue
+				}
+			}
+
+			// Make the prompt fit in the context window of the model, or try the next
+			// model, which may have a larger one
+			if trimmed, err = h.fitContext(timeoutCtx, config, chatRequest, explicitContext); err != nil {
+				if last {
+					return nil, ChatOutput{}, err
+				}
+				continue
+			}
+
+			// Reuse the reply to an identical deterministic request. Built-in tools are
+			// excluded since their results, like the current time, can change.
+			cacheKeyValue = ""
+			if cache != nil && len(builtins.builtin) == 0 && isDeterministic(chatRequest.Options) {
+				if digest, err := modelDigest(timeoutCtx, config, model); err == nil {
+					cacheKeyValue, _ = cacheKey(digest, chatRequest)
+				}
+			}
+			if cacheKeyValue != "" {
+				response, cached = cache.Get(cacheKeyValue)
+			}
+			if !cached {
+				// Use the official client's Chat method with timeout context
+				response, executions, err = h.chatWithTools(timeoutCtx, call, chatRequest)
+
+				// Pull a missing model when the auto-pull policy allows it and ask again.
+				// The download does not count towards the tool's timeout.
+				if isModelNotFound(err) {
+					if err = h.autoPull(ctx, call, model, err); err == nil {
+						var cancelRetry context.CancelFunc
+						timeoutCtx, cancelRetry = context.WithTimeout(ctx, timeouts.Total)
+						defer cancelRetry()
+						response, executions, err = h.chatWithTools(timeoutCtx, call, chatRequest)
+					}
+				}
+			}
+
+			if err == nil || last || !shouldFallback(err) || response.Message.Content != "" || response.Message.Thinking != "" {
+				break
+			}
+		}
 
+		// Keep the text generated before a timeout or cancellation instead of discarding it
+		truncatedReason := ""
 		if err != nil {
-			return nil, ChatOutput{}, fmt.Errorf("failed to chat with Ollama: %w", err)
+			truncatedReason = interruptionReason(ctx, err)
+			if truncatedReason == "" || (response.Message.Content == "" && response.Message.Thinking == "") {
+				return nil, ChatOutput{}, fmt.Errorf("failed to chat with Ollama: %w", err)
+			}
+		} else if response.DoneReason == "length" {
+			truncatedReason = "length"
+		}
+
+		// Validate structured output, asking the model to correct invalid replies.
+		// Replies that only call tools or were interrupted cannot be validated.
+		var structured any
+		if format != nil && len(response.Message.ToolCalls) == 0 && err == nil {
+			for attempt := 0; ; attempt++ {
+				structured, err = format.parse(response.Message.Content)
+				if err == nil {
+					break
+				}
+				if attempt >= config.FormatRetries {
+					return nil, ChatOutput{}, fmt.Errorf("invalid structured output after %d attempts: %w", attempt+1, err)
+				}
+
+				chatRequest.Messages = append(chatRequest.Messages,
+					api.Message{Role: "assistant", Content: response.Message.Content},
+					api.Message{Role: "user", Content: fmt.Sprintf("Your reply was rejected: %v. Reply again with only the corrected JSON.", err)},
+				)
+				retryTrimmed, fitErr := h.fitContext(timeoutCtx, config, chatRequest, explicitContext)
+				if fitErr != nil {
+					return nil, ChatOutput{}, fmt.Errorf("cannot ask for a corrected reply: %w", fitErr)
+				}
+				trimmed += retryTrimmed
+				metrics := response.Metrics
+				var retryExecutions []ToolExecution
+				response, retryExecutions, err = h.chatWithTools(timeoutCtx, call, chatRequest)
+				executions = append(executions, retryExecutions...)
+				addMetrics(&response.Metrics, metrics)
+				if err != nil {
+					return nil, ChatOutput{}, fmt.Errorf("failed to chat with Ollama: %w", err)
+				}
+			}
+		}
+		finalResponse := response.Message.Content
+
+		// Cache complete replies only; a failed write just means the next call is not a hit
+		if cacheKeyValue != "" && !cached && truncatedReason == "" {
+			_ = cache.Put(cacheKeyValue, response)
+		}
+
+		// Record the exchange in the session history
+		if session != nil {
+			newMessages = append(newMessages, api.Message{
+				Role:      "assistant",
+				Content:   finalResponse,
+				ToolCalls: response.Message.ToolCalls,
+			})
+			if err := h.server.GetSessions().Append(session.ID, newMessages...); err != nil {
+				return nil, ChatOutput{}, err
+			}
 		}
 
-		return nil, ChatOutput{Response: finalResponse}, nil
+		return nil, ChatOutput{
+			Model:           chatRequest.Model,
+			Response:        finalResponse,
+			Thinking:        response.Message.Thinking,
+			Usage:           newUsage(response.Metrics, response.DoneReason),
+			Structured:      structured,
+			SessionID:       input.SessionID,
+			ToolCalls:       toolCalls(response.Message.ToolCalls),
+			ToolExecutions:  executions,
+			Truncated:       truncatedReason != "",
+			TruncatedReason: truncatedReason,
+			Cached:          cached,
+			TrimmedMessages: trimmed,
+		}, nil
 	}
 }
 
@@ -157,13 +372,20 @@ func (h *HandlerFactory) CodeHandler() func(context.Context, *mcp.CallToolReques
 	return func(ctx context.Context, req *mcp.CallToolRequest, input CodeInput) (*mcp.CallToolResult, CodeOutput, error) {
 		// Convert CodeInput to ChatInput and use the chat handler
 		chatInput := ChatInput{
-			Model:        "", // Always use the environment variable for code tool
-			Message:      input.Message,
-			ContextSize:  input.ContextSize,
-			SystemPrompt: input.SystemPrompt,
-			Options:      input.Options,
-			KeepAlive:    input.KeepAlive,
-			ToolName:     "code", // Specify that this is the code tool
+			Model:         "", // Always use the environment variable for code tool
+			Message:       input.Message,
+			ContextSize:   input.ContextSize,
+			SystemPrompt:  input.SystemPrompt,
+			Options:       input.Options,
+			KeepAlive:     input.KeepAlive,
+			SessionID:     input.SessionID,
+			Files:         input.Files,
+			Seed:          input.Seed,
+			NumPredict:    input.NumPredict,
+			Stop:          input.Stop,
+			RepeatPenalty: input.RepeatPenalty,
+			MinP:          input.MinP,
+			ToolName:      "code", // Specify that this is the code tool
 		}
 
 		// If no system prompt is provided, use a default one for code
@@ -178,15 +400,600 @@ func (h *HandlerFactory) CodeHandler() func(context.Context, *mcp.CallToolReques
 		}
 
 		// Convert ChatOutput to CodeOutput
-		return result, CodeOutput(chatOutput), nil
+		return result, CodeOutput{
+			Model:           chatOutput.Model,
+			Response:        chatOutput.Response,
+			Thinking:        chatOutput.Thinking,
+			Usage:           chatOutput.Usage,
+			SessionID:       chatOutput.SessionID,
+			Truncated:       chatOutput.Truncated,
+			TruncatedReason: chatOutput.TruncatedReason,
+			Cached:          chatOutput.Cached,
+			TrimmedMessages: chatOutput.TrimmedMessages,
+		}, nil
+	}
+}
+
+// CompleteHandler returns a handler function for the complete tool
+func (h *HandlerFactory) CompleteHandler() func(context.Context, *mcp.CallToolRequest, CompleteInput) (*mcp.CallToolResult, CompleteOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input CompleteInput) (*mcp.CallToolResult, CompleteOutput, error) {
+		// Validate input
+		if err := h.validateCompleteInput(input); err != nil {
+			return nil, CompleteOutput{}, fmt.Errorf("invalid input: %w", err)
+		}
+
+		// Get configuration
+		config := h.server.GetConfig()
+		if config == nil {
+			return nil, CompleteOutput{}, fmt.Errorf("server configuration not found")
+		}
+
+		// Add the tool's timeout to context
+		timeouts := config.GetTimeouts("complete")
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeouts.Total)
+		defer cancel()
+
+		// Use the code model and its fallbacks if not specified
+		candidates := config.modelCandidates("complete", input.Model)
+		for _, model := range candidates {
+			if err := h.validateModelName(model); err != nil {
+				return nil, CompleteOutput{}, err
+			}
+		}
+
+		// Build the generate request. With a suffix, the model's template wraps the
+		// prompt and suffix in its fill-in-the-middle tokens.
+		stream := true
+		generateRequest := &api.GenerateRequest{
+			Model:   candidates[0],
+			Prompt:  input.Prompt,
+			Suffix:  input.Suffix,
+			Raw:     input.Raw,
+			Stream:  &stream,
+			Options: make(map[string]interface{}),
+		}
+
+		// Set context size, or resolve it per model below
+		if input.ContextSize != nil {
+			generateRequest.Options["num_ctx"] = *input.ContextSize
+		}
+		if input.Temperature != nil {
+			generateRequest.Options["temperature"] = *input.Temperature
+		}
+		if input.MaxTokens != nil {
+			generateRequest.Options["num_predict"] = *input.MaxTokens
+		}
+		if len(input.Stop) > 0 {
+			generateRequest.Options["stop"] = input.Stop
+		}
+
+		// Add any additional options
+		for k, v := range input.Options {
+			generateRequest.Options[k] = v
+		}
+
+		// Try the fallback models in turn while a model cannot answer
+		call := &chatCall{req: req, config: config, timeouts: timeouts, progress: newProgressCounter(req)}
+		var response api.GenerateResponse
+		var err error
+		numCtx, explicitContext := generateRequest.Options["num_ctx"]
+		for i, model := range candidates {
+			generateRequest.Model = model
+			if explicitContext {
+				generateRequest.Options["num_ctx"] = numCtx
+			} else {
+				generateRequest.Options["num_ctx"] = h.defaultContextSize(timeoutCtx, config, model)
+			}
+			if generateRequest.KeepAlive, err = config.keepAlive(model, input.KeepAlive); err != nil {
+				return nil, CompleteOutput{}, err
+			}
+			response, err = h.generate(timeoutCtx, call, generateRequest)
+			if err == nil || i == len(candidates)-1 || !shouldFallback(err) || response.Response != "" {
+				break
+			}
+		}
+
+		// Keep the code generated before a timeout or cancellation
+		truncatedReason := ""
+		if err != nil {
+			truncatedReason = interruptionReason(ctx, err)
+			if truncatedReason == "" || response.Response == "" {
+				return nil, CompleteOutput{}, fmt.Errorf("failed to generate with Ollama: %w", err)
+			}
+		} else if response.DoneReason == "length" {
+			truncatedReason = "length"
+		}
+
+		return nil, CompleteOutput{
+			Model:           generateRequest.Model,
+			Completion:      response.Response,
+			Usage:           newUsage(response.Metrics, response.DoneReason),
+			Truncated:       truncatedReason != "",
+			TruncatedReason: truncatedReason,
+		}, nil
+	}
+}
+
+// EmbedHandler returns a handler function for the embed tool
+func (h *HandlerFactory) EmbedHandler() func(context.Context, *mcp.CallToolRequest, EmbedInput) (*mcp.CallToolResult, EmbedOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input EmbedInput) (*mcp.CallToolResult, EmbedOutput, error) {
+		// Validate input
+		if err := h.validateEmbedInput(input); err != nil {
+			return nil, EmbedOutput{}, fmt.Errorf("invalid input: %w", err)
+		}
+
+		// Get configuration
+		config := h.server.GetConfig()
+		if config == nil {
+			return nil, EmbedOutput{}, fmt.Errorf("server configuration not found")
+		}
+
+		// Add the tool's timeout to context
+		timeoutCtx, cancel := context.WithTimeout(ctx, config.GetTimeouts("embed").Total)
+		defer cancel()
+
+		// Use the embed model if not specified
+		modelToUse := input.Model
+		if modelToUse == "" {
+			modelToUse = config.GetModel("embed")
+		}
+		if err := h.validateModelName(modelToUse); err != nil {
+			return nil, EmbedOutput{}, err
+		}
+
+		// Embed the query in the same batch as the inputs
+		texts := input.Input
+		if input.Query != "" {
+			texts = append(append([]string(nil), texts...), input.Query)
+		}
+
+		keepAlive, err := config.keepAlive(modelToUse, nil)
+		if err != nil {
+			return nil, EmbedOutput{}, err
+		}
+
+		response, err := config.Client.Embed(timeoutCtx, &api.EmbedRequest{
+			Model:      modelToUse,
+			Input:      texts,
+			Truncate:   input.Truncate,
+			Dimensions: input.Dimensions,
+			KeepAlive:  keepAlive,
+		})
+		if err != nil {
+			return nil, EmbedOutput{}, fmt.Errorf("failed to embed with Ollama: %w", err)
+		}
+		if len(response.Embeddings) != len(texts) {
+			return nil, EmbedOutput{}, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(response.Embeddings), len(texts))
+		}
+
+		output := EmbedOutput{
+			Model:        modelToUse,
+			Embeddings:   response.Embeddings[:len(input.Input)],
+			PromptTokens: response.PromptEvalCount,
+		}
+		if input.Query != "" {
+			query := response.Embeddings[len(input.Input)]
+			output.Scores = make([]float64, len(input.Input))
+			for i, embedding := range output.Embeddings {
+				output.Scores[i] = cosineSimilarity(embedding, query)
+			}
+		}
+
+		return nil, output, nil
+	}
+}
+
+// IndexDocumentsHandler returns a handler function for the index-documents tool
+func (h *HandlerFactory) IndexDocumentsHandler() func(context.Context, *mcp.CallToolRequest, IndexDocumentsInput) (*mcp.CallToolResult, IndexDocumentsOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input IndexDocumentsInput) (*mcp.CallToolResult, IndexDocumentsOutput, error) {
+		// Validate input
+		if len(input.Paths) == 0 {
+			return nil, IndexDocumentsOutput{}, fmt.Errorf("invalid input: paths cannot be empty")
+		}
+		if input.Model != "" {
+			if err := h.validateModelName(input.Model); err != nil {
+				return nil, IndexDocumentsOutput{}, err
+			}
+		}
+		indexName := input.Index
+		if indexName == "" {
+			indexName = DefaultIndexName
+		}
+
+		// Get configuration
+		config := h.server.GetConfig()
+		if config == nil {
+			return nil, IndexDocumentsOutput{}, fmt.Errorf("server configuration not found")
+		}
+
+		// Add the tool's timeout to context
+		timeoutCtx, cancel := context.WithTimeout(ctx, config.GetTimeouts("index-documents").Total)
+		defer cancel()
+
+		// Find the documents before touching the index
+		files, dirs, err := collectDocuments(config.AllowedRoots, input.Paths)
+		if err != nil {
+			return nil, IndexDocumentsOutput{}, err
+		}
+
+		// Prepare the index under the store lock; files are embedded outside of it so that
+		// searches are not blocked, and saved in batches so that a failure keeps the work done
+		var output IndexDocumentsOutput
+		index, err := h.server.GetIndexes().Update(indexName, func(index *DocumentIndex) error {
+			if input.Reset {
+				index.Model = ""
+				index.Files = make(map[string]*IndexedFile)
+			}
+
+			// Vectors of different models cannot be compared, so an index keeps its model
+			if index.Model != "" && input.Model != "" && input.Model != index.Model {
+				return fmt.Errorf("index %s was built with model %s; set reset to rebuild it with %s", indexName, index.Model, input.Model)
+			}
+			if index.Model == "" {
+				index.Model = input.Model
+				if index.Model == "" {
+					index.Model = config.GetModel("embed")
+				}
+			}
+
+			// Drop files that disappeared from the indexed directories
+			found := make(map[string]bool, len(files))
+			for _, file := range files {
+				found[file] = true
+			}
+			for path := range index.Files {
+				for _, dir := range dirs {
+					if !found[path] && isWithin(dir, path) {
+						delete(index.Files, path)
+						output.Removed = append(output.Removed, path)
+						break
+					}
+				}
+			}
+			sort.Strings(output.Removed)
+			return nil
+		})
+		if err != nil {
+			return nil, IndexDocumentsOutput{}, err
+		}
+
+		model := index.Model
+		pending := make(map[string]*IndexedFile)
+		pendingChunks := 0
+		save := func() error {
+			if len(pending) == 0 {
+				return nil
+			}
+			saved, err := h.server.GetIndexes().Update(indexName, func(index *DocumentIndex) error {
+				if index.Model != model {
+					return fmt.Errorf("index %s was rebuilt with model %s while indexing", indexName, index.Model)
+				}
+				for path, file := range pending {
+					index.Files[path] = file
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			index = saved
+			pending = make(map[string]*IndexedFile)
+			pendingChunks = 0
+			return nil
+		}
+
+		for i, path := range files {
+			notifyProgress(ctx, req, float64(i), float64(len(files)), path)
+
+			content, hash, err := readDocument(path)
+			if err != nil {
+				output.Skipped = append(output.Skipped, fmt.Sprintf("%s: %v", path, err))
+				continue
+			}
+			if existing, ok := index.Files[path]; ok && existing.Hash == hash {
+				output.Unchanged++
+				continue
+			}
+
+			chunks := chunkDocument(content)
+			texts := make([]string, len(chunks))
+			for j, chunk := range chunks {
+				texts[j] = chunk.text
+			}
+			embeddings, tokens, err := h.embedTexts(timeoutCtx, config, model, texts)
+			if err != nil {
+				// Keep the files embedded so far, so that indexing again resumes after them
+				if saveErr := save(); saveErr != nil {
+					return nil, IndexDocumentsOutput{}, fmt.Errorf("failed to embed %s: %w", path, errors.Join(err, saveErr))
+				}
+				return nil, IndexDocumentsOutput{}, fmt.Errorf("failed to embed %s: %w; the files indexed before it were saved, index again to resume", path, err)
+			}
+
+			file := &IndexedFile{Hash: hash, Chunks: make([]DocumentChunk, len(chunks))}
+			for j, chunk := range chunks {
+				file.Chunks[j] = DocumentChunk{
+					StartLine: chunk.startLine,
+					EndLine:   chunk.endLine,
+					Text:      chunk.text,
+					Embedding: embeddings[j],
+				}
+			}
+			pending[path] = file
+			pendingChunks += len(chunks)
+			output.Indexed = append(output.Indexed, path)
+			output.ChunksAdded += len(chunks)
+			output.PromptTokens += tokens
+
+			if pendingChunks >= indexSaveChunks {
+				if err := save(); err != nil {
+					return nil, IndexDocumentsOutput{}, err
+				}
+			}
+		}
+		if err := save(); err != nil {
+			return nil, IndexDocumentsOutput{}, err
+		}
+
+		output.Index = index.Name
+		output.Model = index.Model
+		output.Files = len(index.Files)
+		output.Chunks = index.chunkCount()
+		return nil, output, nil
+	}
+}
+
+// SearchDocumentsHandler returns a handler function for the search-documents tool
+func (h *HandlerFactory) SearchDocumentsHandler() func(context.Context, *mcp.CallToolRequest, SearchDocumentsInput) (*mcp.CallToolResult, SearchDocumentsOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input SearchDocumentsInput) (*mcp.CallToolResult, SearchDocumentsOutput, error) {
+		// Validate input
+		if input.Query == "" {
+			return nil, SearchDocumentsOutput{}, fmt.Errorf("invalid input: query cannot be empty")
+		}
+
+		// Get configuration
+		config := h.server.GetConfig()
+		if config == nil {
+			return nil, SearchDocumentsOutput{}, fmt.Errorf("server configuration not found")
+		}
+
+		// Add the tool's timeout to context
+		timeoutCtx, cancel := context.WithTimeout(ctx, config.GetTimeouts("search-documents").Total)
+		defer cancel()
+
+		matches, err := h.searchDocuments(timeoutCtx, config, input.Index, input.Query, input.TopK)
+		if err != nil {
+			return nil, SearchDocumentsOutput{}, err
+		}
+
+		return nil, SearchDocumentsOutput{Matches: matches}, nil
+	}
+}
+
+// AskDocumentsHandler returns a handler function for the ask-documents tool
+func (h *HandlerFactory) AskDocumentsHandler() func(context.Context, *mcp.CallToolRequest, AskDocumentsInput) (*mcp.CallToolResult, AskDocumentsOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input AskDocumentsInput) (*mcp.CallToolResult, AskDocumentsOutput, error) {
+		// Validate input
+		if input.Question == "" {
+			return nil, AskDocumentsOutput{}, fmt.Errorf("invalid input: question cannot be empty")
+		}
+
+		// Get configuration
+		config := h.server.GetConfig()
+		if config == nil {
+			return nil, AskDocumentsOutput{}, fmt.Errorf("server configuration not found")
+		}
+
+		// Retrieve the chunks closest to the question
+		searchCtx, cancel := context.WithTimeout(ctx, config.GetTimeouts("ask-documents").Total)
+		defer cancel()
+
+		matches, err := h.searchDocuments(searchCtx, config, input.Index, input.Question, input.TopK)
+		if err != nil {
+			return nil, AskDocumentsOutput{}, err
+		}
+
+		// Answer with the chat handler, giving the chunks as numbered excerpts
+		result, chatOutput, err := h.ChatHandler()(ctx, req, ChatInput{
+			Model:        input.Model,
+			Message:      input.Question,
+			SystemPrompt: documentsPrompt(matches),
+			Temperature:  input.Temperature,
+			ContextSize:  input.ContextSize,
+			ToolName:     "chat",
+		})
+		if err != nil {
+			return nil, AskDocumentsOutput{}, err
+		}
+
+		return result, AskDocumentsOutput{
+			Model:           chatOutput.Model,
+			Answer:          chatOutput.Response,
+			Citations:       matches,
+			Usage:           chatOutput.Usage,
+			Truncated:       chatOutput.Truncated,
+			TruncatedReason: chatOutput.TruncatedReason,
+			Cached:          chatOutput.Cached,
+		}, nil
+	}
+}
+
+// ChatBatchHandler returns a handler function for the chat-batch tool
+func (h *HandlerFactory) ChatBatchHandler() func(context.Context, *mcp.CallToolRequest, ChatBatchInput) (*mcp.CallToolResult, ChatBatchOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input ChatBatchInput) (*mcp.CallToolResult, ChatBatchOutput, error) {
+		// Validate input
+		if err := h.validateChatBatchInput(input); err != nil {
+			return nil, ChatBatchOutput{}, fmt.Errorf("invalid input: %w", err)
+		}
+
+		// Get configuration
+		config := h.server.GetConfig()
+		if config == nil {
+			return nil, ChatBatchOutput{}, fmt.Errorf("server configuration not found")
+		}
+
+		// The caller may lower the concurrency but not raise it above the server setting
+		workers := config.BatchConcurrency
+		if workers <= 0 {
+			workers = DefaultBatchConcurrency
+		}
+		if input.Concurrency > 0 && input.Concurrency < workers {
+			workers = input.Concurrency
+		}
+
+		results := make([]BatchResult, len(input.Items))
+		ran := make([]bool, len(input.Items))
+		var done atomic.Int32
+
+		// Each item is a regular chat call with its own timeouts. Progress is reported per item.
+		runWorkers(ctx, workers, len(input.Items), func(i int) {
+			ran[i] = true
+			results[i] = h.runBatchItem(ctx, config, input, i)
+			notifyProgress(ctx, req, float64(done.Add(1)), float64(len(input.Items)), "")
+		})
+
+		output := ChatBatchOutput{Results: results, Usage: &Usage{}}
+		for i := range results {
+			if !ran[i] {
+				results[i] = BatchResult{Index: i, Error: "not run: the batch was cancelled"}
+			}
+			if results[i].Error != "" {
+				output.Failed++
+				continue
+			}
+			output.Succeeded++
+			output.Usage.add(results[i].Usage)
+		}
+
+		return nil, output, nil
+	}
+}
+
+// runBatchItem answers a single item of a chat batch, recording failures in the result
+func (h *HandlerFactory) runBatchItem(ctx context.Context, config *Config, input ChatBatchInput, i int) BatchResult {
+	item := input.Items[i]
+	model := item.Model
+	if model == "" {
+		model = input.Model
+	}
+	result := BatchResult{Index: i, Model: model}
+	if result.Model == "" {
+		result.Model = config.GetModel("chat")
+	}
+
+	systemPrompt := item.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = input.SystemPrompt
+	}
+
+	options := make(map[string]any, len(input.Options)+len(item.Options))
+	for k, v := range input.Options {
+		options[k] = v
+	}
+	for k, v := range item.Options {
+		options[k] = v
+	}
+
+	_, output, err := h.ChatHandler()(ctx, nil, ChatInput{
+		Model:        model,
+		Message:      item.Message,
+		SystemPrompt: systemPrompt,
+		Options:      options,
+		Format:       input.Format,
+		ToolName:     "chat",
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Model = output.Model
+	result.Response = output.Response
+	result.Structured = output.Structured
+	result.Usage = output.Usage
+	result.Truncated = output.Truncated
+	result.TruncatedReason = output.TruncatedReason
+	result.Cached = output.Cached
+	return result
+}
+
+// CompareModelsHandler returns a handler function for the compare-models tool
+func (h *HandlerFactory) CompareModelsHandler() func(context.Context, *mcp.CallToolRequest, CompareModelsInput) (*mcp.CallToolResult, CompareModelsOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input CompareModelsInput) (*mcp.CallToolResult, CompareModelsOutput, error) {
+		// Validate input
+		if err := h.validateCompareModelsInput(input); err != nil {
+			return nil, CompareModelsOutput{}, fmt.Errorf("invalid input: %w", err)
+		}
+
+		// Sequential runs load one model at a time, unloading each model once it has
+		// answered so that the next one gets the whole VRAM. Parallel runs are bounded
+		// by the batch concurrency.
+		workers := 1
+		keepAlive := input.KeepAlive
+		if input.Parallel {
+			workers = h.server.GetConfig().BatchConcurrency
+			if workers <= 0 {
+				workers = DefaultBatchConcurrency
+			}
+		} else if keepAlive == nil {
+			unload := "0"
+			keepAlive = &unload
+		}
+
+		answers := make([]ModelAnswer, len(input.Models))
+		ran := make([]bool, len(input.Models))
+		var done atomic.Int32
+
+		runWorkers(ctx, workers, len(input.Models), func(i int) {
+			ran[i] = true
+			answer := ModelAnswer{Model: input.Models[i]}
+
+			start := time.Now()
+			_, output, err := h.ChatHandler()(ctx, nil, ChatInput{
+				Model:        input.Models[i],
+				Message:      input.Message,
+				SystemPrompt: input.SystemPrompt,
+				Options:      input.Options,
+				Temperature:  input.Temperature,
+				ContextSize:  input.ContextSize,
+				KeepAlive:    keepAlive,
+				ToolName:     "chat",
+			})
+			answer.LatencyMs = time.Since(start).Milliseconds()
+
+			if err != nil {
+				answer.Error = err.Error()
+			} else {
+				answer.Response = output.Response
+				answer.Thinking = output.Thinking
+				answer.Usage = output.Usage
+				answer.Truncated = output.Truncated
+				answer.TruncatedReason = output.TruncatedReason
+
+				// The latency of a cached answer says nothing about the model
+				if output.Cached {
+					answer.Cached = true
+					answer.LatencyMs = 0
+				}
+			}
+			answers[i] = answer
+			notifyProgress(ctx, req, float64(done.Add(1)), float64(len(input.Models)), input.Models[i])
+		})
+
+		for i := range answers {
+			if !ran[i] {
+				answers[i] = ModelAnswer{Model: input.Models[i], Error: "not run: the comparison was cancelled"}
+			}
+		}
+
+		return nil, CompareModelsOutput{Answers: answers}, nil
 	}
 }
 
 // ListModelsHandler returns a handler function for the list-models tool
 func (h *HandlerFactory) ListModelsHandler() func(context.Context, *mcp.CallToolRequest, ListModelsInput) (*mcp.CallToolResult, ListModelsOutput, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, input ListModelsInput) (*mcp.CallToolResult, ListModelsOutput, error) {
-		// Add timeout to context
-		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		// Add the tool's timeout to context
+		timeoutCtx, cancel := context.WithTimeout(ctx, h.server.GetConfig().GetTimeouts("list-models").Total)
 		defer cancel()
 
 		// Get the Ollama client from server
@@ -196,7 +1003,12 @@ func (h *HandlerFactory) ListModelsHandler() func(context.Context, *mcp.CallTool
 		}
 
 		// Get the list of models
-		response, err := client.List(timeoutCtx)
+		var response *api.ListResponse
+		err := h.server.GetConfig().Retry.do(timeoutCtx, func() error {
+			var err error
+			response, err = client.List(timeoutCtx)
+			return err
+		})
 		if err != nil {
 			return nil, ListModelsOutput{}, fmt.Errorf("failed to list models: %w", err)
 		}
@@ -220,8 +1032,8 @@ func (h *HandlerFactory) ListModelsHandler() func(context.Context, *mcp.CallTool
 // ModelInfoHandler returns a handler function for the model-info tool
 func (h *HandlerFactory) ModelInfoHandler() func(context.Context, *mcp.CallToolRequest, ModelInfoInput) (*mcp.CallToolResult, ModelInfoOutput, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, input ModelInfoInput) (*mcp.CallToolResult, ModelInfoOutput, error) {
-		// Add timeout to context
-		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		// Add the tool's timeout to context
+		timeoutCtx, cancel := context.WithTimeout(ctx, h.server.GetConfig().GetTimeouts("model-info").Total)
 		defer cancel()
 
 		// Validate input
@@ -236,7 +1048,12 @@ func (h *HandlerFactory) ModelInfoHandler() func(context.Context, *mcp.CallToolR
 		}
 
 		// Get the model information
-		response, err := client.Show(timeoutCtx, &api.ShowRequest{Name: input.Name})
+		var response *api.ShowResponse
+		err := h.server.GetConfig().Retry.do(timeoutCtx, func() error {
+			var err error
+			response, err = client.Show(timeoutCtx, &api.ShowRequest{Name: input.Name})
+			return err
+		})
 		if err != nil {
 			return nil, ModelInfoOutput{}, fmt.Errorf("failed to get model info: %w", err)
 		}
@@ -264,42 +1081,354 @@ func (h *HandlerFactory) PullModelHandler() func(context.Context, *mcp.CallToolR
 			return nil, PullModelOutput{}, err
 		}
 
-		// Get the Ollama client from server
-		client := h.server.GetClient()
-		if client == nil {
-			return nil, PullModelOutput{}, fmt.Errorf("ollama client not initialized")
+		// Pull the model, reporting the download progress unless disabled
+		var progress *progressCounter
+		if !input.NoProgress {
+			progress = newProgressCounter(req)
+		}
+		err := h.pullModel(ctx, progress, input.Name, input.Insecure)
+		if err != nil {
+			return nil, PullModelOutput{}, fmt.Errorf("failed to pull model %s: %w", input.Name, err)
 		}
 
-		// Set up the pull request
-		pullRequest := &api.PullRequest{
-			Model:    input.Name,
-			Insecure: input.Insecure,
+		return nil, PullModelOutput{
+			Status:  "success",
+			Message: fmt.Sprintf("Successfully pulled model %s", input.Name),
+		}, nil
+	}
+}
+
+// ModelsResourceHandler returns a handler reading the ollama://models resource
+func (h *HandlerFactory) ModelsResourceHandler() mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		_, output, err := h.ListModelsHandler()(ctx, nil, ListModelsInput{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range output.Models {
+			output.Models[i].URI = ModelResourceURI(output.Models[i].Name)
 		}
 
-		// Pull the model - let the library handle everything
-		// Note: Pull operations can take a long time, so we don't add a timeout here
-		err := client.Pull(ctx, pullRequest, func(progress api.ProgressResponse) error {
-			// Simply pass through - the library handles progress internally
-			return nil
-		})
+		return jsonResource(req.Params.URI, output)
+	}
+}
 
+// ModelResourceHandler returns a handler reading the ollama://models/{name} and
+// ollama://models/{name}/modelfile resources
+func (h *HandlerFactory) ModelResourceHandler() mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		name, modelfile, err := parseModelResourceURI(req.Params.URI)
 		if err != nil {
-			return nil, PullModelOutput{}, fmt.Errorf("failed to pull model %s: %w", input.Name, err)
+			return nil, mcp.ResourceNotFoundError(req.Params.URI)
 		}
 
-		return nil, PullModelOutput{
+		_, output, err := h.ModelInfoHandler()(ctx, nil, ModelInfoInput{Name: name})
+		if err != nil {
+			var statusErr api.StatusError
+			if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+				return nil, mcp.ResourceNotFoundError(req.Params.URI)
+			}
+			return nil, err
+		}
+
+		if modelfile {
+			return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{
+				URI:      req.Params.URI,
+				MIMEType: "text/plain",
+				Text:     output.Modelfile,
+			}}}, nil
+		}
+		return jsonResource(req.Params.URI, output)
+	}
+}
+
+// CreateSessionHandler returns a handler function for the create-session tool
+func (h *HandlerFactory) CreateSessionHandler() func(context.Context, *mcp.CallToolRequest, CreateSessionInput) (*mcp.CallToolResult, CreateSessionOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input CreateSessionInput) (*mcp.CallToolResult, CreateSessionOutput, error) {
+		session, err := h.server.GetSessions().Create(input.SystemPrompt)
+		if err != nil {
+			return nil, CreateSessionOutput{}, fmt.Errorf("failed to create session: %w", err)
+		}
+
+		return nil, CreateSessionOutput{Session: session.summary()}, nil
+	}
+}
+
+// ListSessionsHandler returns a handler function for the list-sessions tool
+func (h *HandlerFactory) ListSessionsHandler() func(context.Context, *mcp.CallToolRequest, ListSessionsInput) (*mcp.CallToolResult, ListSessionsOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input ListSessionsInput) (*mcp.CallToolResult, ListSessionsOutput, error) {
+		sessions := h.server.GetSessions().List()
+
+		summaries := make([]SessionSummary, len(sessions))
+		for i, session := range sessions {
+			summaries[i] = session.summary()
+		}
+
+		return nil, ListSessionsOutput{Sessions: summaries}, nil
+	}
+}
+
+// SessionInfoHandler returns a handler function for the session-info tool
+func (h *HandlerFactory) SessionInfoHandler() func(context.Context, *mcp.CallToolRequest, SessionInfoInput) (*mcp.CallToolResult, SessionInfoOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input SessionInfoInput) (*mcp.CallToolResult, SessionInfoOutput, error) {
+		session, err := h.server.GetSessions().Get(input.SessionID)
+		if err != nil {
+			return nil, SessionInfoOutput{}, err
+		}
+
+		messages := make([]SessionMessage, len(session.Messages))
+		for i, message := range session.Messages {
+			messages[i] = SessionMessage{
+				Role:    message.Role,
+				Content: message.Content,
+			}
+		}
+
+		return nil, SessionInfoOutput{Session: session.summary(), Messages: messages}, nil
+	}
+}
+
+// ResetSessionHandler returns a handler function for the reset-session tool
+func (h *HandlerFactory) ResetSessionHandler() func(context.Context, *mcp.CallToolRequest, ResetSessionInput) (*mcp.CallToolResult, ResetSessionOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input ResetSessionInput) (*mcp.CallToolResult, ResetSessionOutput, error) {
+		if err := h.server.GetSessions().Reset(input.SessionID); err != nil {
+			return nil, ResetSessionOutput{}, err
+		}
+
+		return nil, ResetSessionOutput{
 			Status:  "success",
-			Message: fmt.Sprintf("Successfully pulled model %s", input.Name),
+			Message: fmt.Sprintf("Session %s has been reset", input.SessionID),
+		}, nil
+	}
+}
+
+// DeleteSessionHandler returns a handler function for the delete-session tool
+func (h *HandlerFactory) DeleteSessionHandler() func(context.Context, *mcp.CallToolRequest, DeleteSessionInput) (*mcp.CallToolResult, DeleteSessionOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input DeleteSessionInput) (*mcp.CallToolResult, DeleteSessionOutput, error) {
+		if err := h.server.GetSessions().Delete(input.SessionID); err != nil {
+			return nil, DeleteSessionOutput{}, err
+		}
+
+		return nil, DeleteSessionOutput{
+			Status:  "success",
+			Message: fmt.Sprintf("Session %s has been deleted", input.SessionID),
 		}, nil
 	}
 }
 
+// ClearCacheHandler returns a handler function for the cache-clear tool
+func (h *HandlerFactory) ClearCacheHandler() func(context.Context, *mcp.CallToolRequest, ClearCacheInput) (*mcp.CallToolResult, ClearCacheOutput, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input ClearCacheInput) (*mcp.CallToolResult, ClearCacheOutput, error) {
+		cache := h.server.GetCache()
+		if cache == nil {
+			return nil, ClearCacheOutput{}, fmt.Errorf("the response cache is disabled")
+		}
+
+		removed, err := cache.Clear()
+		if err != nil {
+			return nil, ClearCacheOutput{}, err
+		}
+
+		return nil, ClearCacheOutput{Removed: removed}, nil
+	}
+}
+
+// Ollama helper methods
+
+// chatCall carries the settings shared by the Ollama requests of a single tool call
+type chatCall struct {
+	req      *mcp.CallToolRequest
+	config   *Config
+	timeouts Timeouts
+	tools    *toolset
+	progress *progressCounter
+}
+
+// chat sends a streamed chat request to Ollama and merges the returned chunks into a single
+// response. Each chunk is forwarded to the caller as a progress notification when it asked
+// for progress, and the request is aborted when the first-token or idle timeout expires.
+func (h *HandlerFactory) chat(ctx context.Context, call *chatCall, chatRequest *api.ChatRequest) (api.ChatResponse, error) {
+	var merged api.ChatResponse
+	var content, thinking strings.Builder
+	var calls []api.ToolCall
+
+	err := call.config.Retry.do(ctx, func() error {
+		received := false
+		var filter thinkFilter
+		watchCtx, watch := startWatchdog(ctx, call.timeouts)
+		err := call.config.Client.Chat(watchCtx, chatRequest, func(response api.ChatResponse) error {
+			received = true
+			watch.tick()
+			content.WriteString(response.Message.Content)
+			thinking.WriteString(response.Message.Thinking)
+			calls = append(calls, response.Message.ToolCalls...)
+			if response.Done {
+				merged = response
+			}
+
+			generated := 0
+			if response.Message.Content != "" || response.Message.Thinking != "" || len(response.Message.ToolCalls) > 0 {
+				generated = 1
+			}
+			visible := filter.visible(response.Message.Content)
+			if response.Done {
+				visible += filter.flush()
+			}
+			call.progress.advance(ctx, generated, visible)
+			return nil
+		})
+		if timeoutErr := watch.stop(watchCtx); timeoutErr != nil {
+			err = timeoutErr
+		} else if err == nil && ctx.Err() != nil {
+			// The client ends the stream silently when its context is cancelled
+			err = ctx.Err()
+		}
+
+		// Retrying after part of the response was streamed would repeat it
+		if received {
+			return noRetry(err)
+		}
+		return err
+	})
+	merged.Message.Role = "assistant"
+	merged.Message.Content = content.String()
+	merged.Message.Thinking = thinking.String()
+	merged.Message.ToolCalls = calls
+
+	// Separate reasoning that the model emitted inline in its content
+	if hasInlineThinking(merged.Message.Content) {
+		answer, inline := splitThinking(merged.Message.Content)
+		merged.Message.Content = answer
+		if merged.Message.Thinking == "" {
+			merged.Message.Thinking = inline
+		} else if inline != "" {
+			merged.Message.Thinking += "\n\n" + inline
+		}
+	}
+
+	return merged, err
+}
+
+// generate sends a streamed generate request to Ollama and merges the returned chunks,
+// with the same progress notifications and timeouts as chat
+func (h *HandlerFactory) generate(ctx context.Context, call *chatCall, generateRequest *api.GenerateRequest) (api.GenerateResponse, error) {
+	var merged api.GenerateResponse
+	var text strings.Builder
+
+	err := call.config.Retry.do(ctx, func() error {
+		received := false
+		watchCtx, watch := startWatchdog(ctx, call.timeouts)
+		err := call.config.Client.Generate(watchCtx, generateRequest, func(response api.GenerateResponse) error {
+			received = true
+			watch.tick()
+			text.WriteString(response.Response)
+			if response.Done {
+				merged = response
+			}
+
+			generated := 0
+			if response.Response != "" || response.Thinking != "" {
+				generated = 1
+			}
+			call.progress.advance(ctx, generated, response.Response)
+			return nil
+		})
+		if timeoutErr := watch.stop(watchCtx); timeoutErr != nil {
+			err = timeoutErr
+		} else if err == nil && ctx.Err() != nil {
+			// The client ends the stream silently when its context is cancelled
+			err = ctx.Err()
+		}
+
+		// Retrying after part of the response was streamed would repeat it
+		if received {
+			return noRetry(err)
+		}
+		return err
+	})
+	merged.Response = text.String()
+
+	return merged, err
+}
+
+// embedTexts embeds texts in batches and returns one vector per text with the number of tokens
+// embedded. Batches failing with a transient error are retried.
+func (h *HandlerFactory) embedTexts(ctx context.Context, config *Config, model string, texts []string) ([][]float32, int, error) {
+	keepAlive, err := config.keepAlive(model, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	embeddings := make([][]float32, 0, len(texts))
+	tokens := 0
+	for start := 0; start < len(texts); start += embedBatchSize {
+		batch := texts[start:min(start+embedBatchSize, len(texts))]
+		var response *api.EmbedResponse
+		err := config.Retry.do(ctx, func() error {
+			var err error
+			response, err = config.Client.Embed(ctx, &api.EmbedRequest{Model: model, Input: batch, KeepAlive: keepAlive})
+			return err
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(response.Embeddings) != len(batch) {
+			return nil, 0, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(response.Embeddings), len(batch))
+		}
+		embeddings = append(embeddings, response.Embeddings...)
+		tokens += response.PromptEvalCount
+	}
+	return embeddings, tokens, nil
+}
+
+// searchDocuments returns the topK chunks of an index most similar to the query
+func (h *HandlerFactory) searchDocuments(ctx context.Context, config *Config, indexName, query string, topK int) ([]DocumentMatch, error) {
+	if indexName == "" {
+		indexName = DefaultIndexName
+	}
+	if topK < 0 {
+		return nil, fmt.Errorf("invalid input: top_k must be positive")
+	}
+	if topK == 0 {
+		topK = DefaultSearchResults
+	}
+
+	index, err := h.server.GetIndexes().Load(indexName)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings, _, err := h.embedTexts(ctx, config, index.Model, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	return index.search(embeddings[0], topK), nil
+}
+
+// jsonResource returns a resource holding the JSON encoding of a value
+func jsonResource(uri string, value any) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{
+		URI:      uri,
+		MIMEType: "application/json",
+		Text:     string(data),
+	}}}, nil
+}
+
 // Validation helper methods
 
 func (h *HandlerFactory) validateChatInput(input ChatInput) error {
-	if input.Message == "" {
+	if input.Message == "" && len(input.ToolResults) == 0 {
 		return fmt.Errorf("message cannot be empty")
 	}
+	for i, result := range input.ToolResults {
+		if result.Name == "" {
+			return fmt.Errorf("tool result %d: name cannot be empty", i+1)
+		}
+	}
 
 	// Validate context size if provided
 	if input.ContextSize != nil && *input.ContextSize <= 0 {
@@ -321,6 +1450,168 @@ func (h *HandlerFactory) validateChatInput(input ChatInput) error {
 		return fmt.Errorf("top_k must be non-negative")
 	}
 
+	// Validate the other typed sampling options if provided
+	if input.MinP != nil && (*input.MinP < 0 || *input.MinP > 1.0) {
+		return fmt.Errorf("min_p must be between 0 and 1.0")
+	}
+	if input.NumPredict != nil && (*input.NumPredict == 0 || *input.NumPredict < -1) {
+		return fmt.Errorf("num_predict must be positive, or -1 for no limit")
+	}
+	if input.RepeatPenalty != nil && *input.RepeatPenalty < 0 {
+		return fmt.Errorf("repeat_penalty must be non-negative")
+	}
+
+	// Validate the additional options against Ollama's model options
+	if err := validateOptions(input.Options); err != nil {
+		return err
+	}
+
+	// Validate keep_alive if provided
+	if input.KeepAlive != nil {
+		if _, err := ParseKeepAlive(*input.KeepAlive); err != nil {
+			return err
+		}
+	}
+
+	// Validate think if provided
+	if _, err := parseThink(input.Think); err != nil {
+		return err
+	}
+
+	// Validate format if provided
+	if _, err := parseFormat(input.Format); err != nil {
+		return err
+	}
+
+	// Validate images if provided
+	for i, image := range input.Images {
+		if err := image.validate(); err != nil {
+			return fmt.Errorf("image %d: %w", i+1, err)
+		}
+	}
+
+	// Validate tools if provided
+	if _, _, err := buildTools(input.Tools, input.BuiltinTools); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (h *HandlerFactory) validateCompleteInput(input CompleteInput) error {
+	if input.Prompt == "" && input.Suffix == "" {
+		return fmt.Errorf("prompt and suffix cannot both be empty")
+	}
+
+	// In raw mode the prompt is sent as is, so the suffix would be ignored
+	if input.Raw && input.Suffix != "" {
+		return fmt.Errorf("suffix cannot be used in raw mode; put the fill-in-the-middle tokens in the prompt")
+	}
+
+	if input.ContextSize != nil && *input.ContextSize <= 0 {
+		return fmt.Errorf("context size must be positive")
+	}
+	if input.Temperature != nil && (*input.Temperature < 0 || *input.Temperature > 2.0) {
+		return fmt.Errorf("temperature must be between 0 and 2.0")
+	}
+	if input.MaxTokens != nil && *input.MaxTokens <= 0 {
+		return fmt.Errorf("max_tokens must be positive")
+	}
+	if err := validateOptions(input.Options); err != nil {
+		return err
+	}
+	if input.KeepAlive != nil {
+		if _, err := ParseKeepAlive(*input.KeepAlive); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *HandlerFactory) validateEmbedInput(input EmbedInput) error {
+	if len(input.Input) == 0 {
+		return fmt.Errorf("input cannot be empty")
+	}
+	for i, text := range input.Input {
+		if text == "" {
+			return fmt.Errorf("input %d is empty", i+1)
+		}
+	}
+
+	if input.Dimensions < 0 {
+		return fmt.Errorf("dimensions must be non-negative")
+	}
+
+	return nil
+}
+
+func (h *HandlerFactory) validateChatBatchInput(input ChatBatchInput) error {
+	if len(input.Items) == 0 {
+		return fmt.Errorf("items cannot be empty")
+	}
+	if len(input.Items) > maxBatchItems {
+		return fmt.Errorf("a batch cannot have more than %d items", maxBatchItems)
+	}
+	if input.Concurrency < 0 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+	if _, err := parseFormat(input.Format); err != nil {
+		return err
+	}
+	if err := validateOptions(input.Options); err != nil {
+		return err
+	}
+
+	for i, item := range input.Items {
+		if item.Message == "" {
+			return fmt.Errorf("item %d: message cannot be empty", i)
+		}
+		if err := validateOptions(item.Options); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *HandlerFactory) validateCompareModelsInput(input CompareModelsInput) error {
+	if input.Message == "" {
+		return fmt.Errorf("message cannot be empty")
+	}
+	if len(input.Models) == 0 {
+		return fmt.Errorf("models cannot be empty")
+	}
+	if len(input.Models) > maxCompareModels {
+		return fmt.Errorf("cannot compare more than %d models", maxCompareModels)
+	}
+
+	seen := make(map[string]bool, len(input.Models))
+	for _, model := range input.Models {
+		if err := h.validateModelName(model); err != nil {
+			return err
+		}
+		if seen[model] {
+			return fmt.Errorf("model %s is listed twice", model)
+		}
+		seen[model] = true
+	}
+
+	if input.ContextSize != nil && *input.ContextSize <= 0 {
+		return fmt.Errorf("context size must be positive")
+	}
+	if input.Temperature != nil && (*input.Temperature < 0 || *input.Temperature > 2.0) {
+		return fmt.Errorf("temperature must be between 0 and 2.0")
+	}
+	if err := validateOptions(input.Options); err != nil {
+		return err
+	}
+	if input.KeepAlive != nil {
+		if _, err := ParseKeepAlive(*input.KeepAlive); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,28 @@
+package core_test
+
+import (
+	"context"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/ollama/ollama/api"
+)
+
+// userMessage builds a user chat message
+func userMessage(content string) api.Message {
+	return api.Message{Role: "user", Content: content}
+}
+
+// connectClient connects an in-memory MCP client to the given server
+func connectClient(server *mcp.Server, opts *mcp.ClientOptions) *mcp.ClientSession {
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+
+	_, err := server.Connect(context.Background(), serverTransport, nil)
+	Expect(err).NotTo(HaveOccurred())
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, opts)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	Expect(err).NotTo(HaveOccurred())
+	return session
+}
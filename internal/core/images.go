@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/types/model"
+)
+
+// maxImageBytes limits the size of a single image attached to a chat request
+const maxImageBytes = 20 * 1024 * 1024
+
+// ImageInput is an image attached to a chat message. It accepts MCP image content
+// ({"type": "image", "data": ..., "mimeType": ...}), a bare base64 string in data,
+// or the path of a local file inside the allowed roots.
+type ImageInput struct {
+	Type     string `json:"type,omitempty" jsonschema:"content type, \"image\" for MCP image content (optional)"`
+	Data     string `json:"data,omitempty" jsonschema:"base64-encoded image data or data URL"`
+	MimeType string `json:"mimeType,omitempty" jsonschema:"MIME type of the image (optional)"`
+	Path     string `json:"path,omitempty" jsonschema:"path of a local image file inside the allowed roots"`
+}
+
+// validate checks that the image is described by exactly one source
+func (i ImageInput) validate() error {
+	if i.Type != "" && i.Type != "image" {
+		return fmt.Errorf("unsupported image content type: %s", i.Type)
+	}
+	if (i.Data == "") == (i.Path == "") {
+		return fmt.Errorf("image must have either data or path")
+	}
+	return nil
+}
+
+// loadImages decodes or reads the images attached to a chat request
+func loadImages(config *Config, inputs []ImageInput) ([]api.ImageData, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	images := make([]api.ImageData, 0, len(inputs))
+	for i, input := range inputs {
+		if err := input.validate(); err != nil {
+			return nil, fmt.Errorf("image %d: %w", i+1, err)
+		}
+
+		var data []byte
+		var err error
+		if input.Path != "" {
+			data, err = readImageFile(config.AllowedRoots, input.Path)
+		} else {
+			data, err = decodeImageData(input.Data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %w", i+1, err)
+		}
+
+		if contentType := http.DetectContentType(data); !strings.HasPrefix(contentType, "image/") {
+			return nil, fmt.Errorf("image %d: data is not an image (detected %s)", i+1, contentType)
+		}
+		images = append(images, data)
+	}
+	return images, nil
+}
+
+// decodeImageData decodes base64 image data, with or without a data URL prefix
+func decodeImageData(data string) ([]byte, error) {
+	if strings.HasPrefix(data, "data:") {
+		_, encoded, ok := strings.Cut(data, ",")
+		if !ok {
+			return nil, fmt.Errorf("invalid data URL")
+		}
+		data = encoded
+	}
+
+	if base64.StdEncoding.DecodedLen(len(data)) > maxImageBytes {
+		return nil, fmt.Errorf("image exceeds %d bytes", maxImageBytes)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 image data: %w", err)
+	}
+	return decoded, nil
+}
+
+// readImageFile reads an image file inside the allowed roots
+func readImageFile(roots []string, path string) ([]byte, error) {
+	resolved, err := resolveAllowedPath(roots, path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(resolved)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("image exceeds %d bytes", maxImageBytes)
+	}
+	return data, nil
+}
+
+// requireVision checks that a model can handle image inputs
+func requireVision(ctx context.Context, config *Config, modelName string) error {
+	var response *api.ShowResponse
+	err := config.Retry.do(ctx, func() error {
+		var err error
+		response, err = config.Client.Show(ctx, &api.ShowRequest{Model: modelName})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get model capabilities: %w", err)
+	}
+
+	if !slices.Contains(response.Capabilities, model.CapabilityVision) {
+		return fmt.Errorf("model %s does not support image inputs; use a vision model such as llava or qwen2.5vl", modelName)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package core_test
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/types/model"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+// pngHeader is enough of a PNG file for content type detection
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+var _ = Describe("Image inputs", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.models["llava"] = &api.ShowResponse{Capabilities: []model.Capability{model.CapabilityCompletion, model.CapabilityVision}}
+		ollama.models["text-only"] = &api.ShowResponse{Capabilities: []model.Capability{model.CapabilityCompletion}}
+		config = &core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32000,
+			ChatModel:   "llava",
+			KeepAlive:   "1m",
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should attach base64 and MCP image content to the user message", func() {
+		encoded := base64.StdEncoding.EncodeToString(pngHeader)
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			Message: "describe",
+			Images: []core.ImageInput{
+				{Data: encoded},
+				{Type: "image", Data: "data:image/png;base64," + encoded, MimeType: "image/png"},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		message := ollama.ChatRequests()[0].Messages[0]
+		Expect(message.Images).To(HaveLen(2))
+		Expect([]byte(message.Images[0])).To(Equal(pngHeader))
+	})
+
+	It("should read image files inside the allowed roots", func() {
+		root := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(root, "shot.png"), pngHeader, 0o600)).To(Succeed())
+		config.AllowedRoots = []string{root}
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			Message: "describe",
+			Images:  []core.ImageInput{{Path: "shot.png"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ollama.ChatRequests()[0].Messages[0].Images).To(HaveLen(1))
+	})
+
+	It("should refuse image files without allowed roots", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			Message: "describe",
+			Images:  []core.ImageInput{{Path: "/etc/hostname"}},
+		})
+		Expect(err).To(MatchError(ContainSubstring("no allowed roots")))
+	})
+
+	It("should reject models without vision support", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			Model:   "text-only",
+			Message: "describe",
+			Images:  []core.ImageInput{{Data: base64.StdEncoding.EncodeToString(pngHeader)}},
+		})
+		Expect(err).To(MatchError(ContainSubstring("does not support image inputs")))
+		Expect(ollama.ChatRequests()).To(BeEmpty())
+	})
+
+	It("should reject data that is not an image", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			Message: "describe",
+			Images:  []core.ImageInput{{Data: base64.StdEncoding.EncodeToString([]byte("plain text"))}},
+		})
+		Expect(err).To(MatchError(ContainSubstring("not an image")))
+	})
+})
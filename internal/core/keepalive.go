@@ -0,0 +1,79 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// ParseKeepAlive parses how long Ollama keeps a model loaded after a request: a duration
+// such as "10m", a number of seconds, "0" to unload the model right away, or a negative
+// value such as "-1" to keep it loaded indefinitely, returned as -1
+func ParseKeepAlive(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if d, err = time.ParseDuration(value); err != nil {
+		return 0, fmt.Errorf("invalid keep-alive %q: expected a duration such as 5m, a number of seconds, or -1", value)
+	}
+
+	if d < 0 {
+		return -1, nil
+	}
+	return d, nil
+}
+
+// ParseModelKeepAlive parses a comma-separated list of model=keep-alive pairs,
+// such as "qwen3-coder:30b=2h,llava=0"
+func ParseModelKeepAlive(spec string) (map[string]time.Duration, error) {
+	policies := make(map[string]time.Duration)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		model, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid keep-alive policy %q: expected model=duration", entry)
+		}
+		model = strings.TrimSpace(model)
+		d, err := ParseKeepAlive(value)
+		if err != nil {
+			return nil, fmt.Errorf("keep-alive for %s: %w", model, err)
+		}
+		policies[withDefaultTag(model)] = d
+	}
+	return policies, nil
+}
+
+// keepAlive returns the keep-alive sent with a request for a model: the requested one,
+// else the policy of the model, else the default keep-alive
+func (c *Config) keepAlive(model string, requested *string) (*api.Duration, error) {
+	if requested != nil {
+		d, err := ParseKeepAlive(*requested)
+		if err != nil {
+			return nil, err
+		}
+		return &api.Duration{Duration: d}, nil
+	}
+
+	if d, ok := c.ModelKeepAlive[withDefaultTag(model)]; ok {
+		return &api.Duration{Duration: d}, nil
+	}
+
+	// Without a default, Ollama applies its own
+	if c.KeepAlive == "" {
+		return nil, nil
+	}
+	d, err := ParseKeepAlive(c.KeepAlive)
+	if err != nil {
+		return nil, err
+	}
+	return &api.Duration{Duration: d}, nil
+}
@@ -0,0 +1,134 @@
+package core_test
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Keep-alive", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		config = &core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32000,
+			CodeModel:   "qwen3-coder:30b",
+			ChatModel:   "gpt-oss:20b",
+			KeepAlive:   "0",
+			ModelKeepAlive: map[string]time.Duration{
+				"qwen3-coder:30b": 2 * time.Hour,
+				"llama3.2:latest": -1,
+			},
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	sentKeepAlive := func() *api.Duration {
+		requests := ollama.ChatRequests()
+		return requests[len(requests)-1].KeepAlive
+	}
+
+	It("should parse durations, seconds and -1", func() {
+		Expect(core.ParseKeepAlive("10m")).To(Equal(10 * time.Minute))
+		Expect(core.ParseKeepAlive("300")).To(Equal(5 * time.Minute))
+		Expect(core.ParseKeepAlive("0")).To(Equal(time.Duration(0)))
+		Expect(core.ParseKeepAlive("-1")).To(Equal(time.Duration(-1)))
+		Expect(core.ParseKeepAlive("-5m")).To(Equal(time.Duration(-1)))
+
+		_, err := core.ParseKeepAlive("forever")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should parse keep-alive policies per model", func() {
+		policies, err := core.ParseModelKeepAlive("qwen3-coder:30b=2h, llava=0, llama3.2=-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policies).To(Equal(map[string]time.Duration{
+			"qwen3-coder:30b": 2 * time.Hour,
+			"llava:latest":    0,
+			"llama3.2:latest": -1,
+		}))
+
+		_, err = core.ParseModelKeepAlive("llava=soon")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should send the default keep-alive in the request rather than the options", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentKeepAlive()).To(Equal(&api.Duration{Duration: 0}))
+		Expect(ollama.ChatRequests()[0].Options).NotTo(HaveKey("keep_alive"))
+	})
+
+	It("should apply the policy of the model", func() {
+		_, _, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{Message: "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentKeepAlive()).To(Equal(&api.Duration{Duration: 2 * time.Hour}))
+
+		_, _, err = factory.ChatHandler()(context.Background(), nil, core.ChatInput{Model: "llama3.2", Message: "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		// -1 is sent as is, which Ollama reads as forever
+		Expect(sentKeepAlive().Duration).To(Equal(time.Duration(math.MaxInt64)))
+	})
+
+	It("should prefer the requested keep-alive", func() {
+		keepAlive := "90"
+		_, _, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{Message: "hello", KeepAlive: &keepAlive})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentKeepAlive()).To(Equal(&api.Duration{Duration: 90 * time.Second}))
+	})
+
+	It("should reject an invalid keep-alive", func() {
+		keepAlive := "a while"
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello", KeepAlive: &keepAlive})
+		Expect(err).To(MatchError(ContainSubstring("invalid keep-alive")))
+		Expect(ollama.ChatRequests()).To(BeEmpty())
+	})
+
+	It("should send the keep-alive of completions", func() {
+		_, _, err := factory.CompleteHandler()(context.Background(), nil, core.CompleteInput{Prompt: "func add("})
+		Expect(err).NotTo(HaveOccurred())
+		request := ollama.GenerateRequests()[0]
+		Expect(request.KeepAlive).To(Equal(&api.Duration{Duration: 2 * time.Hour}))
+		Expect(request.Options).NotTo(HaveKey("keep_alive"))
+	})
+
+	It("should send the keep-alive of document embeddings", func() {
+		root := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(root, "notes.md"), []byte("Ollama runs models locally.\n"), 0o644)).To(Succeed())
+		config.AllowedRoots = []string{root}
+		config.IndexDir = filepath.Join(root, "indexes")
+		config.EmbedModel = "nomic-embed-text"
+		config.ModelKeepAlive["nomic-embed-text:latest"] = 30 * time.Minute
+		factory = core.NewHandlerFactory(core.NewServer(config))
+
+		_, _, err := factory.IndexDocumentsHandler()(context.Background(), nil, core.IndexDocumentsInput{Paths: []string{root}})
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = factory.SearchDocumentsHandler()(context.Background(), nil, core.SearchDocumentsInput{Query: "ollama"})
+		Expect(err).NotTo(HaveOccurred())
+
+		requests := ollama.EmbedRequests()
+		Expect(requests).To(HaveLen(2))
+		for _, request := range requests {
+			Expect(request.KeepAlive).To(Equal(&api.Duration{Duration: 30 * time.Minute}))
+		}
+	})
+})
@@ -1,5 +1,42 @@
 package core
 
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Model resource URIs. Model names are percent-encoded in the URIs, so that
+// qwen3-coder:30b becomes ollama://models/qwen3-coder%3A30b.
+const (
+	ModelsResourceURI         = "ollama://models"
+	ModelResourceTemplate     = "ollama://models/{name}"
+	ModelfileResourceTemplate = "ollama://models/{name}/modelfile"
+	modelResourcePrefix       = ModelsResourceURI + "/"
+	modelfileResourceSuffix   = "/modelfile"
+)
+
+// ModelResourceURI returns the URI of the resource describing a model. Every character
+// but the unreserved ones is escaped, as required to match the URI templates.
+func ModelResourceURI(name string) string {
+	return modelResourcePrefix + url.QueryEscape(name)
+}
+
+// parseModelResourceURI extracts the model name from a model or modelfile resource URI
+func parseModelResourceURI(uri string) (name string, modelfile bool, err error) {
+	escaped, ok := strings.CutPrefix(uri, modelResourcePrefix)
+	if !ok {
+		return "", false, fmt.Errorf("not a model resource: %s", uri)
+	}
+	escaped, modelfile = strings.CutSuffix(escaped, modelfileResourceSuffix)
+
+	name, err = url.QueryUnescape(escaped)
+	if err != nil || name == "" {
+		return "", false, fmt.Errorf("invalid model resource: %s", uri)
+	}
+	return name, modelfile, nil
+}
+
 // Model represents an Ollama model
 type Model struct {
 	Name        string `json:"name" jsonschema:"name of the model"`
@@ -7,6 +44,7 @@ type Model struct {
 	ModifiedAt  string `json:"modified_at" jsonschema:"timestamp when the model was last modified"`
 	Digest      string `json:"digest" jsonschema:"digest of the model"`
 	Description string `json:"description" jsonschema:"description of the model"`
+	URI         string `json:"uri,omitempty" jsonschema:"URI of the model resource"`
 }
 
 // ListModelsInput represents the input for the ListModels function
@@ -40,3 +78,11 @@ type ModelInfoOutput struct {
 
 // Note: ModelInfo is deprecated. Use HandlerFactory.ModelInfoHandler() instead.
 // This function is kept for backward compatibility but should not be used directly.
+
+// withDefaultTag returns a model name with the latest tag when it has no tag
+func withDefaultTag(name string) string {
+	if strings.Contains(name[strings.LastIndex(name, "/")+1:], ":") {
+		return name
+	}
+	return name + ":latest"
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// optionKinds maps the JSON name of each Ollama model option to the kind of its value
+var optionKinds = func() map[string]reflect.Type {
+	kinds := make(map[string]reflect.Type)
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(api.Options{})) {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		kind := field.Type
+		if kind.Kind() == reflect.Pointer {
+			kind = kind.Elem()
+		}
+		kinds[name] = kind
+	}
+	return kinds
+}()
+
+// validateOptions checks that model options are known to Ollama and have the right type,
+// suggesting the closest known names for unknown options
+func validateOptions(options map[string]any) error {
+	var unknown []string
+	for name, value := range options {
+		kind, ok := optionKinds[name]
+		if !ok {
+			if suggestion := suggestOption(name); suggestion != "" {
+				name = fmt.Sprintf("%s (did you mean %s?)", name, suggestion)
+			}
+			unknown = append(unknown, name)
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		if err := checkOptionType(kind, value); err != nil {
+			return fmt.Errorf("option %s %w", name, err)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown options: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// checkOptionType checks that an option value, set from Go or decoded from JSON, fits the option's type
+func checkOptionType(kind reflect.Type, value any) error {
+	switch kind.Kind() {
+	case reflect.Int:
+		// JSON numbers are decoded as float64, which toInt would truncate
+		if n, ok := value.(float64); ok && n != math.Trunc(n) {
+			return fmt.Errorf("must be an integer")
+		}
+		if _, ok := toInt(value); !ok {
+			return fmt.Errorf("must be an integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := toFloat(value); !ok {
+			return fmt.Errorf("must be a number")
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("must be a boolean")
+		}
+	case reflect.Slice:
+		switch values := value.(type) {
+		case []string:
+		case []any:
+			for _, v := range values {
+				if _, ok := v.(string); !ok {
+					return fmt.Errorf("must be a list of strings")
+				}
+			}
+		default:
+			return fmt.Errorf("must be a list of strings")
+		}
+	}
+	return nil
+}
+
+// toFloat converts a number to a float64
+func toFloat(value any) (float64, bool) {
+	switch value := value.(type) {
+	case float32:
+		return float64(value), true
+	case float64:
+		return value, true
+	case json.Number:
+		n, err := value.Float64()
+		return n, err == nil
+	}
+	if n, ok := toInt(value); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// suggestOption returns the known option closest to an unknown name, or "" when none is close
+func suggestOption(name string) string {
+	best, bestDistance := "", 3
+	for option := range optionKinds {
+		if distance := editDistance(name, option); distance < bestDistance || distance == bestDistance && option < best {
+			best, bestDistance = option, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Damerau-Levenshtein distance between two strings, counting
+// a transposition of adjacent characters as a single edit
+func editDistance(a, b string) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
@@ -0,0 +1,107 @@
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Model options", func() {
+	var (
+		ollama  *fakeOllama
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		factory = core.NewHandlerFactory(core.NewServer(&core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32000,
+			CodeModel:   "test-code-model",
+			ChatModel:   "test-chat-model",
+			KeepAlive:   "1m",
+		}))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	// decoded returns options as an MCP client sends them, decoded from JSON
+	decoded := func(data string) map[string]any {
+		var options map[string]any
+		Expect(json.Unmarshal([]byte(data), &options)).To(Succeed())
+		return options
+	}
+
+	It("should send the typed options", func() {
+		seed, numPredict := 7, 256
+		repeatPenalty, minP := float32(1.1), float32(0.05)
+
+		_, _, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{
+			Message:       "hello",
+			Seed:          &seed,
+			NumPredict:    &numPredict,
+			Stop:          []string{"\n\n"},
+			RepeatPenalty: &repeatPenalty,
+			MinP:          &minP,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		options := ollama.ChatRequests()[0].Options
+		Expect(options["seed"]).To(BeNumerically("==", 7))
+		Expect(options["num_predict"]).To(BeNumerically("==", 256))
+		Expect(options["stop"]).To(Equal([]any{"\n\n"}))
+		Expect(options["repeat_penalty"]).To(BeNumerically("~", 1.1, 1e-6))
+		Expect(options["min_p"]).To(BeNumerically("~", 0.05, 1e-6))
+	})
+
+	It("should accept known options", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			Message: "hello",
+			Options: decoded(`{"num_batch": 512, "use_mmap": false, "presence_penalty": 0.5, "stop": ["END"]}`),
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should reject unknown options with suggestions", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{
+			Message: "hello",
+			Options: decoded(`{"num_predcit": 10, "temprature": 0.2, "flavor": "vanilla"}`),
+		})
+		Expect(err).To(MatchError(ContainSubstring("unknown options: flavor, num_predcit (did you mean num_predict?), temprature (did you mean temperature?)")))
+		Expect(ollama.ChatRequests()).To(BeEmpty())
+	})
+
+	It("should reject options of the wrong type", func() {
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello", Options: decoded(`{"seed": 1.5}`)})
+		Expect(err).To(MatchError(ContainSubstring("option seed must be an integer")))
+
+		_, _, err = factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello", Options: decoded(`{"stop": "END"}`)})
+		Expect(err).To(MatchError(ContainSubstring("option stop must be a list of strings")))
+
+		_, _, err = factory.CompleteHandler()(context.Background(), nil, core.CompleteInput{Prompt: "x", Options: decoded(`{"top_p": "high"}`)})
+		Expect(err).To(MatchError(ContainSubstring("option top_p must be a number")))
+	})
+
+	It("should reject invalid typed options", func() {
+		numPredict := 0
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello", NumPredict: &numPredict})
+		Expect(err).To(MatchError(ContainSubstring("num_predict must be positive")))
+
+		minP := float32(2)
+		_, _, err = factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello", MinP: &minP})
+		Expect(err).To(MatchError(ContainSubstring("min_p must be between 0 and 1.0")))
+	})
+
+	It("should validate the options of every batch item", func() {
+		_, _, err := factory.ChatBatchHandler()(context.Background(), nil, core.ChatBatchInput{
+			Items: []core.BatchItem{{Message: "a"}, {Message: "b", Options: map[string]any{"sed": 1}}},
+		})
+		Expect(err).To(MatchError(ContainSubstring("item 1: unknown options: sed (did you mean seed?)")))
+	})
+})
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// progressToken returns the progress token sent by the caller, if any
+func progressToken(req *mcp.CallToolRequest) any {
+	if req == nil || req.Params == nil || req.Session == nil {
+		return nil
+	}
+	return req.Params.GetProgressToken()
+}
+
+// notifyProgress sends a progress notification to the caller when it asked for one.
+// Notifications are best effort: a failure to deliver one does not abort the tool call.
+func notifyProgress(ctx context.Context, req *mcp.CallToolRequest, progress, total float64, message string) {
+	token := progressToken(req)
+	if token == nil {
+		return
+	}
+
+	_ = req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: token,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+}
+
+// progressCounter counts the progress of a tool call across every Ollama request made
+// for it: model pulls, tool rounds, format retries and fallback models. The progress
+// value is the number of chunks generated so far, about one token each since Ollama
+// streams a token per chunk, plus one per pull update. It is not an exact token count,
+// which the usage of the result gives.
+type progressCounter struct {
+	req *mcp.CallToolRequest
+
+	mu    sync.Mutex
+	count int
+}
+
+// newProgressCounter creates the progress counter of a tool call
+func newProgressCounter(req *mcp.CallToolRequest) *progressCounter {
+	return &progressCounter{req: req}
+}
+
+// advance adds n to the progress and, when the message is not empty, sends it to the
+// caller with the new progress value. Nothing is sent when the caller did not ask for progress.
+func (p *progressCounter) advance(ctx context.Context, n int, message string) {
+	if progressToken(p.req) == nil {
+		return
+	}
+
+	// Sending under the lock keeps the notifications in order
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.count += n
+	if message != "" {
+		notifyProgress(ctx, p.req, float64(p.count), 0, message)
+	}
+}
@@ -0,0 +1,270 @@
+package core_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Chat streaming", func() {
+	var (
+		ollama *fakeOllama
+		server *mcp.Server
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{
+				{Message: api.Message{Role: "assistant", Content: "Hello"}},
+				{Message: api.Message{Role: "assistant", Content: ", world"}},
+				{Message: api.Message{Role: "assistant"}, Done: true, Metrics: api.Metrics{EvalCount: 3}},
+			}
+		}
+
+		factory := core.NewHandlerFactory(core.NewServer(&core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32000,
+			ChatModel:   "test-chat-model",
+			KeepAlive:   "1m",
+
+			MaxToolIterations: 8,
+		}))
+		server = mcp.NewServer(&mcp.Implementation{Name: "test", Version: "test"}, nil)
+		mcp.AddTool(server, &mcp.Tool{Name: "chat"}, factory.ChatHandler())
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should forward partial text as progress notifications", func() {
+		var (
+			mu       sync.Mutex
+			messages []string
+		)
+		session := connectClient(server, &mcp.ClientOptions{
+			ProgressNotificationHandler: func(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+				mu.Lock()
+				defer mu.Unlock()
+				messages = append(messages, req.Params.Message)
+			},
+		})
+		defer func() { _ = session.Close() }()
+
+		params := &mcp.CallToolParams{
+			Meta:      mcp.Meta{"progressToken": "token"},
+			Name:      "chat",
+			Arguments: map[string]any{"model": "test-chat-model", "message": "hi"},
+		}
+		result, err := session.CallTool(context.Background(), params)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.IsError).To(BeFalse())
+		Expect(result.StructuredContent).To(HaveKeyWithValue("response", "Hello, world"))
+
+		Eventually(func() string {
+			mu.Lock()
+			defer mu.Unlock()
+			return strings.Join(messages, "")
+		}).Should(Equal("Hello, world"))
+		Expect(*ollama.ChatRequests()[0].Stream).To(BeTrue())
+	})
+
+	It("should count the reasoning chunks without sending them", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{
+				{Message: api.Message{Role: "assistant", Thinking: "Let me "}},
+				{Message: api.Message{Role: "assistant", Thinking: "think."}},
+				{Message: api.Message{Role: "assistant", Content: "42"}},
+				{Message: api.Message{Role: "assistant"}, Done: true, Metrics: api.Metrics{EvalCount: 3}},
+			}
+		}
+
+		var (
+			mu       sync.Mutex
+			messages []string
+			progress []float64
+		)
+		session := connectClient(server, &mcp.ClientOptions{
+			ProgressNotificationHandler: func(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+				mu.Lock()
+				defer mu.Unlock()
+				messages = append(messages, req.Params.Message)
+				progress = append(progress, req.Params.Progress)
+			},
+		})
+		defer func() { _ = session.Close() }()
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Meta:      mcp.Meta{"progressToken": "token"},
+			Name:      "chat",
+			Arguments: map[string]any{"model": "test-chat-model", "message": "hi"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.IsError).To(BeFalse())
+
+		Eventually(func() []float64 {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]float64(nil), progress...)
+		}).Should(Equal([]float64{3}))
+		Consistently(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), messages...)
+		}, "50ms").Should(Equal([]string{"42"}))
+	})
+
+	It("should leave inline reasoning out of the progress messages", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{
+				{Message: api.Message{Role: "assistant", Content: "<thi"}},
+				{Message: api.Message{Role: "assistant", Content: "nk>hmm</think>"}},
+				{Message: api.Message{Role: "assistant", Content: "Hello <"}},
+				{Message: api.Message{Role: "assistant", Content: "b>world"}, Done: true},
+			}
+		}
+
+		var (
+			mu       sync.Mutex
+			messages []string
+		)
+		session := connectClient(server, &mcp.ClientOptions{
+			ProgressNotificationHandler: func(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+				mu.Lock()
+				defer mu.Unlock()
+				messages = append(messages, req.Params.Message)
+			},
+		})
+		defer func() { _ = session.Close() }()
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Meta:      mcp.Meta{"progressToken": "token"},
+			Name:      "chat",
+			Arguments: map[string]any{"model": "test-chat-model", "message": "hi"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.StructuredContent).To(HaveKeyWithValue("thinking", "hmm"))
+
+		Eventually(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), messages...)
+		}).Should(Equal([]string{"Hello ", "<b>world"}))
+	})
+
+	It("should leave out reasoning opened by the prompt template", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{
+				{Message: api.Message{Role: "assistant", Content: "reas"}},
+				{Message: api.Message{Role: "assistant", Content: "oning</th"}},
+				{Message: api.Message{Role: "assistant", Content: "ink>ans"}},
+				{Message: api.Message{Role: "assistant", Content: "wer <"}},
+				{Message: api.Message{Role: "assistant"}, Done: true},
+			}
+		}
+
+		var (
+			mu       sync.Mutex
+			messages []string
+		)
+		session := connectClient(server, &mcp.ClientOptions{
+			ProgressNotificationHandler: func(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+				mu.Lock()
+				defer mu.Unlock()
+				messages = append(messages, req.Params.Message)
+			},
+		})
+		defer func() { _ = session.Close() }()
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Meta:      mcp.Meta{"progressToken": "token"},
+			Name:      "chat",
+			Arguments: map[string]any{"model": "test-chat-model", "message": "hi"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.StructuredContent).To(HaveKeyWithValue("thinking", "reasoning"))
+
+		// The partial tag at the end of the stream is text
+		Eventually(func() string {
+			mu.Lock()
+			defer mu.Unlock()
+			return strings.Join(messages, "")
+		}).Should(Equal("answer <"))
+		mu.Lock()
+		defer mu.Unlock()
+		for _, message := range messages {
+			Expect(message).NotTo(ContainSubstring("reasoning"))
+		}
+	})
+
+	It("should keep increasing the progress across tool rounds", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			if req.Messages[len(req.Messages)-1].Role == "tool" {
+				return []api.ChatResponse{
+					{Message: api.Message{Role: "assistant", Content: "20"}},
+					{Message: api.Message{Role: "assistant"}, Done: true, Metrics: api.Metrics{EvalCount: 20}},
+				}
+			}
+			return []api.ChatResponse{{
+				Message: api.Message{Role: "assistant", Content: "Let me compute.", ToolCalls: []api.ToolCall{{Function: api.ToolCallFunction{
+					Name:      "calculator",
+					Arguments: map[string]any{"expression": "4 * 5"},
+				}}}},
+				Done:    true,
+				Metrics: api.Metrics{EvalCount: 20},
+			}}
+		}
+
+		var (
+			mu       sync.Mutex
+			progress []float64
+		)
+		session := connectClient(server, &mcp.ClientOptions{
+			ProgressNotificationHandler: func(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+				mu.Lock()
+				defer mu.Unlock()
+				progress = append(progress, req.Params.Progress)
+			},
+		})
+		defer func() { _ = session.Close() }()
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Meta:      mcp.Meta{"progressToken": "token"},
+			Name:      "chat",
+			Arguments: map[string]any{"model": "test-chat-model", "message": "4 * 5?", "builtin_tools": []string{"calculator"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.IsError).To(BeFalse())
+
+		Eventually(func() []float64 {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]float64(nil), progress...)
+		}).Should(Equal([]float64{1, 2}))
+	})
+
+	It("should not send notifications without a progress token", func() {
+		var notifications atomic.Int32
+		session := connectClient(server, &mcp.ClientOptions{
+			ProgressNotificationHandler: func(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+				notifications.Add(1)
+			},
+		})
+		defer func() { _ = session.Close() }()
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "chat", Arguments: map[string]any{"model": "test-chat-model", "message": "hi"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.StructuredContent).To(HaveKeyWithValue("response", "Hello, world"))
+		Consistently(notifications.Load, "100ms").Should(BeZero())
+	})
+})
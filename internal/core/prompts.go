@@ -0,0 +1,252 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// PromptDefinition describes a reusable prompt. Each message is a Go text/template
+// executed with the prompt arguments; arguments the caller omits are empty strings.
+type PromptDefinition struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description,omitempty"`
+	Arguments   []PromptArgumentDefinition `json:"arguments,omitempty"`
+	Messages    []PromptMessageTemplate    `json:"messages"`
+}
+
+// PromptArgumentDefinition describes an argument of a prompt
+type PromptArgumentDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptMessageTemplate is a message of a prompt, with a role of user or assistant
+type PromptMessageTemplate struct {
+	Role     string `json:"role"`
+	Template string `json:"template"`
+}
+
+// registeredPrompt is a prompt definition with its parsed templates
+type registeredPrompt struct {
+	definition PromptDefinition
+	templates  []*template.Template
+}
+
+// PromptRegistry holds the prompts exposed through the MCP prompts API
+type PromptRegistry struct {
+	prompts map[string]*registeredPrompt
+}
+
+// NewPromptRegistry creates a registry holding the built-in prompts
+func NewPromptRegistry() *PromptRegistry {
+	r := &PromptRegistry{prompts: make(map[string]*registeredPrompt)}
+	for _, definition := range builtinPrompts {
+		if err := r.Add(definition); err != nil {
+			panic(fmt.Sprintf("invalid built-in prompt %s: %v", definition.Name, err))
+		}
+	}
+	return r
+}
+
+// Add parses a prompt definition and adds it to the registry, replacing any
+// prompt with the same name
+func (r *PromptRegistry) Add(definition PromptDefinition) error {
+	if definition.Name == "" {
+		return fmt.Errorf("prompt name cannot be empty")
+	}
+	if len(definition.Messages) == 0 {
+		return fmt.Errorf("prompt %s has no messages", definition.Name)
+	}
+
+	seen := make(map[string]bool, len(definition.Arguments))
+	for _, argument := range definition.Arguments {
+		if argument.Name == "" {
+			return fmt.Errorf("prompt %s has an argument without a name", definition.Name)
+		}
+		if seen[argument.Name] {
+			return fmt.Errorf("prompt %s declares argument %s twice", definition.Name, argument.Name)
+		}
+		seen[argument.Name] = true
+	}
+
+	prompt := &registeredPrompt{definition: definition}
+	for i, message := range definition.Messages {
+		if message.Role != "user" && message.Role != "assistant" {
+			return fmt.Errorf("prompt %s message %d: role must be user or assistant", definition.Name, i+1)
+		}
+
+		// Referencing an undeclared argument is an error rather than an empty string
+		tmpl, err := template.New(fmt.Sprintf("%s#%d", definition.Name, i+1)).Option("missingkey=error").Parse(message.Template)
+		if err != nil {
+			return fmt.Errorf("prompt %s message %d: %w", definition.Name, i+1, err)
+		}
+		prompt.templates = append(prompt.templates, tmpl)
+	}
+
+	r.prompts[definition.Name] = prompt
+	return nil
+}
+
+// LoadDir adds the prompts defined in the JSON files of a directory. Each file holds
+// a single prompt definition; user prompts replace built-in prompts with the same name.
+func (r *PromptRegistry) LoadDir(dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("failed to read prompts directory: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(paths)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var definition PromptDefinition
+		if err := json.Unmarshal(data, &definition); err != nil {
+			return fmt.Errorf("invalid prompt file %s: %w", path, err)
+		}
+		if definition.Name == "" {
+			definition.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		if err := r.Add(definition); err != nil {
+			return fmt.Errorf("invalid prompt file %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Names returns the names of the registered prompts, sorted
+func (r *PromptRegistry) Names() []string {
+	names := make([]string, 0, len(r.prompts))
+	for name := range r.prompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render executes the templates of a prompt with the given arguments
+func (r *PromptRegistry) Render(name string, arguments map[string]string) (*mcp.GetPromptResult, error) {
+	prompt, ok := r.prompts[name]
+	if !ok {
+		return nil, fmt.Errorf("prompt not found: %s", name)
+	}
+
+	data := make(map[string]string, len(prompt.definition.Arguments))
+	for _, argument := range prompt.definition.Arguments {
+		value := arguments[argument.Name]
+		if argument.Required && value == "" {
+			return nil, fmt.Errorf("missing required argument %q for prompt %s", argument.Name, name)
+		}
+		data[argument.Name] = value
+	}
+
+	result := &mcp.GetPromptResult{Description: prompt.definition.Description}
+	for i, tmpl := range prompt.templates {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("failed to render prompt %s: %w", name, err)
+		}
+		result.Messages = append(result.Messages, &mcp.PromptMessage{
+			Role:    mcp.Role(prompt.definition.Messages[i].Role),
+			Content: &mcp.TextContent{Text: b.String()},
+		})
+	}
+	return result, nil
+}
+
+// Register adds every prompt of the registry to an MCP server
+func (r *PromptRegistry) Register(server *mcp.Server) {
+	for _, name := range r.Names() {
+		definition := r.prompts[name].definition
+
+		prompt := &mcp.Prompt{Name: definition.Name, Description: definition.Description}
+		for _, argument := range definition.Arguments {
+			prompt.Arguments = append(prompt.Arguments, &mcp.PromptArgument{
+				Name:        argument.Name,
+				Description: argument.Description,
+				Required:    argument.Required,
+			})
+		}
+
+		server.AddPrompt(prompt, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return r.Render(name, req.Params.Arguments)
+		})
+	}
+}
+
+// builtinPrompts are the prompts available without a prompts directory
+var builtinPrompts = []PromptDefinition{
+	{
+		Name:        "review-go-diff",
+		Description: "Review a Go diff for bugs, style and missing tests",
+		Arguments: []PromptArgumentDefinition{
+			{Name: "diff", Description: "the unified diff to review", Required: true},
+			{Name: "focus", Description: "aspects to pay particular attention to, such as concurrency or error handling"},
+		},
+		Messages: []PromptMessageTemplate{{
+			Role: "user",
+			Template: `Review the following Go diff as an experienced Go reviewer.
+Point out bugs, race conditions, error handling mistakes, non-idiomatic code and missing tests.
+For each finding, quote the relevant line, explain the problem and suggest a fix.
+{{- if .focus}}
+Pay particular attention to: {{.focus}}.
+{{- end}}
+If the change looks good, say so briefly.
+
+` + "```diff\n{{.diff}}\n```",
+		}},
+	},
+	{
+		Name:        "write-table-tests",
+		Description: "Write table-driven Go tests for a function",
+		Arguments: []PromptArgumentDefinition{
+			{Name: "code", Description: "the Go code to test", Required: true},
+			{Name: "function", Description: "the function to focus the tests on"},
+			{Name: "framework", Description: "test framework to use, the standard testing package by default"},
+		},
+		Messages: []PromptMessageTemplate{{
+			Role: "user",
+			Template: `Write table-driven tests for the following Go code
+{{- if .function}} focusing on {{.function}}{{end}}, using {{if .framework}}{{.framework}}{{else}}the standard testing package{{end}}.
+Cover the normal cases, edge cases and error paths. Give each case a descriptive name,
+and run the cases as subtests. Reply with a complete _test.go file only.
+
+` + "```go\n{{.code}}\n```",
+		}},
+	},
+	{
+		Name:        "explain-error",
+		Description: "Explain an error message and how to fix it",
+		Arguments: []PromptArgumentDefinition{
+			{Name: "error", Description: "the error message or stack trace", Required: true},
+			{Name: "code", Description: "the code that produced the error"},
+			{Name: "language", Description: "the programming language or tool involved"},
+		},
+		Messages: []PromptMessageTemplate{{
+			Role: "user",
+			Template: `Explain the following {{if .language}}{{.language}} {{end}}error: what it means, its most likely causes, and how to fix it.
+
+` + "```\n{{.error}}\n```" + `
+{{- if .code}}
+
+It was produced by this code:
+
+` + "```\n{{.code}}\n```" + `
+{{- end}}`,
+		}},
+	},
+}
@@ -0,0 +1,119 @@
+package core_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Prompts", func() {
+	var (
+		registry *core.PromptRegistry
+		dir      string
+	)
+
+	writePrompt := func(name, content string) {
+		Expect(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)).To(Succeed())
+	}
+
+	promptText := func(result *mcp.GetPromptResult, i int) string {
+		return result.Messages[i].Content.(*mcp.TextContent).Text
+	}
+
+	BeforeEach(func() {
+		registry = core.NewPromptRegistry()
+		dir = GinkgoT().TempDir()
+	})
+
+	It("should provide the built-in prompts", func() {
+		Expect(registry.Names()).To(ContainElements("explain-error", "review-go-diff", "write-table-tests"))
+	})
+
+	It("should render a prompt with its arguments", func() {
+		result, err := registry.Render("review-go-diff", map[string]string{"diff": "+func f() {}", "focus": "error handling"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Messages).To(HaveLen(1))
+		Expect(result.Messages[0].Role).To(Equal(mcp.Role("user")))
+		Expect(promptText(result, 0)).To(ContainSubstring("```diff\n+func f() {}\n```"))
+		Expect(promptText(result, 0)).To(ContainSubstring("Pay particular attention to: error handling."))
+
+		result, err = registry.Render("review-go-diff", map[string]string{"diff": "+x"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(promptText(result, 0)).NotTo(ContainSubstring("attention"))
+	})
+
+	It("should require the required arguments", func() {
+		_, err := registry.Render("explain-error", map[string]string{"code": "x := 1"})
+		Expect(err).To(MatchError(ContainSubstring(`missing required argument "error"`)))
+	})
+
+	It("should load user prompts that add to and override the built-in prompts", func() {
+		writePrompt("summarize.json", `{
+			"description": "Summarize a text",
+			"arguments": [{"name": "text", "required": true}],
+			"messages": [
+				{"role": "user", "template": "Summarize: {{.text}}"},
+				{"role": "assistant", "template": "Here is a summary:"}
+			]
+		}`)
+		writePrompt("explain-error.json", `{
+			"name": "explain-error",
+			"arguments": [{"name": "error", "required": true}],
+			"messages": [{"role": "user", "template": "Why {{.error}}?"}]
+		}`)
+		writePrompt("notes.txt", "not a prompt")
+
+		Expect(registry.LoadDir(dir)).To(Succeed())
+
+		result, err := registry.Render("summarize", map[string]string{"text": "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(promptText(result, 0)).To(Equal("Summarize: hello"))
+		Expect(result.Messages[1].Role).To(Equal(mcp.Role("assistant")))
+
+		result, err = registry.Render("explain-error", map[string]string{"error": "nil map"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(promptText(result, 0)).To(Equal("Why nil map?"))
+	})
+
+	It("should reject invalid prompt files", func() {
+		writePrompt("broken.json", `{"messages": [{"role": "user", "template": "{{.text"}]}`)
+		Expect(registry.LoadDir(dir)).To(MatchError(ContainSubstring("broken.json")))
+	})
+
+	It("should fail on arguments the prompt does not declare", func() {
+		writePrompt("typo.json", `{"arguments": [{"name": "text"}], "messages": [{"role": "user", "template": "{{.txt}}"}]}`)
+		Expect(registry.LoadDir(dir)).To(Succeed())
+
+		_, err := registry.Render("typo", nil)
+		Expect(err).To(MatchError(ContainSubstring("txt")))
+	})
+
+	It("should expose the prompts through the MCP prompts API", func() {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "test"}, nil)
+		registry.Register(server)
+		session := connectClient(server, nil)
+		defer func() { _ = session.Close() }()
+
+		list, err := session.ListPrompts(context.Background(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, prompt := range list.Prompts {
+			names = append(names, prompt.Name)
+		}
+		Expect(names).To(ContainElement("write-table-tests"))
+
+		result, err := session.GetPrompt(context.Background(), &mcp.GetPromptParams{
+			Name:      "write-table-tests",
+			Arguments: map[string]string{"code": "func Add(a, b int) int { return a + b }", "function": "Add"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(promptText(result, 0)).To(ContainSubstring("focusing on Add, using the standard testing package"))
+	})
+})
@@ -0,0 +1,459 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Document indexing limits
+const (
+	// DefaultIndexName is the index used when a tool call does not name one
+	DefaultIndexName = "default"
+
+	// DefaultSearchResults is the number of chunks returned by a search
+	DefaultSearchResults = 5
+
+	// maxDocumentBytes skips files too large to be documentation
+	maxDocumentBytes = 1024 * 1024
+
+	// maxChunkLines and maxChunkChars bound the size of a chunk; consecutive
+	// chunks share chunkOverlapLines lines so that no passage is cut in half
+	maxChunkLines     = 40
+	maxChunkChars     = 1500
+	chunkOverlapLines = 5
+
+	// embedBatchSize is the number of chunks embedded per Ollama request
+	embedBatchSize = 32
+
+	// indexSaveChunks is the number of newly embedded chunks after which the index
+	// is saved, so that a failure does not lose the files embedded before it
+	indexSaveChunks = 256
+)
+
+// documentExtensions lists the file types indexed when walking a directory
+var documentExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".mdx":      true,
+	".txt":      true,
+	".rst":      true,
+	".adoc":     true,
+}
+
+var indexNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// DocumentChunk is a range of lines of an indexed file with its embedding
+type DocumentChunk struct {
+	StartLine int       `json:"start_line"`
+	EndLine   int       `json:"end_line"`
+	Text      string    `json:"text"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// IndexedFile holds the chunks of a file and the hash of the content they were built from
+type IndexedFile struct {
+	Hash   string          `json:"hash"`
+	Chunks []DocumentChunk `json:"chunks"`
+}
+
+// DocumentIndex is an on-disk vector index of local documents
+type DocumentIndex struct {
+	Name      string                  `json:"name"`
+	Model     string                  `json:"model"`
+	Files     map[string]*IndexedFile `json:"files"`
+	UpdatedAt time.Time               `json:"updated_at"`
+}
+
+// chunkCount returns the number of chunks in the index
+func (i *DocumentIndex) chunkCount() int {
+	count := 0
+	for _, file := range i.Files {
+		count += len(file.Chunks)
+	}
+	return count
+}
+
+// IndexStore loads and saves document indexes as JSON files in a directory
+type IndexStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewIndexStore creates a store keeping its indexes in dir
+func NewIndexStore(dir string) *IndexStore {
+	return &IndexStore{dir: dir}
+}
+
+// Load returns the index with the given name, or an error if it was never built
+func (s *IndexStore) Load(name string) (*DocumentIndex, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.read(name)
+	if err != nil {
+		return nil, err
+	}
+	if index == nil {
+		return nil, fmt.Errorf("index not found: %s; build it with index-documents", name)
+	}
+	return index, nil
+}
+
+// Update loads the index with the given name, creating it if needed, applies fn
+// and saves the result. Updates of the same store are serialized, so fn must not make
+// slow calls such as embedding requests.
+func (s *IndexStore) Update(name string, fn func(*DocumentIndex) error) (*DocumentIndex, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.read(name)
+	if err != nil {
+		return nil, err
+	}
+	if index == nil {
+		index = &DocumentIndex{Name: name, Files: make(map[string]*IndexedFile)}
+	}
+
+	if err := fn(index); err != nil {
+		return nil, err
+	}
+	index.UpdatedAt = time.Now()
+
+	if err := s.write(index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// read loads an index from disk, returning nil if it does not exist
+func (s *IndexStore) read(name string) (*DocumentIndex, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %w", name, err)
+	}
+
+	var index DocumentIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %w", name, err)
+	}
+	if index.Files == nil {
+		index.Files = make(map[string]*IndexedFile)
+	}
+	return &index, nil
+}
+
+// write saves an index atomically so that a crash never leaves a partial file behind
+func (s *IndexStore) write(index *DocumentIndex) error {
+	path, err := s.path(index.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, index.Name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write index %s: %w", index.Name, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write index %s: %w", index.Name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write index %s: %w", index.Name, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write index %s: %w", index.Name, err)
+	}
+	return nil
+}
+
+// path returns the file of the index with the given name
+func (s *IndexStore) path(name string) (string, error) {
+	if s.dir == "" {
+		return "", fmt.Errorf("no index directory configured")
+	}
+	if !indexNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid index name: %s", name)
+	}
+	return filepath.Join(s.dir, name+".json"), nil
+}
+
+// DefaultIndexDir returns the directory used to store document indexes by default
+func DefaultIndexDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ollama-mcp", "indexes")
+}
+
+// textChunk is a range of lines of a document, before embedding
+type textChunk struct {
+	startLine int
+	endLine   int
+	text      string
+}
+
+// chunkDocument splits a document into overlapping chunks of whole lines.
+// Blank chunks are dropped.
+func chunkDocument(content string) []textChunk {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var chunks []textChunk
+	for start := 0; start < len(lines); {
+		end, size := start, 0
+		for end < len(lines) && end-start < maxChunkLines {
+			if end > start && size+len(lines[end]) > maxChunkChars {
+				break
+			}
+			size += len(lines[end]) + 1
+			end++
+		}
+
+		// Report the range without its surrounding blank lines
+		first, last := start, end
+		for first < last && strings.TrimSpace(lines[first]) == "" {
+			first++
+		}
+		for last > first && strings.TrimSpace(lines[last-1]) == "" {
+			last--
+		}
+		if first < last {
+			chunks = append(chunks, textChunk{
+				startLine: first + 1,
+				endLine:   last,
+				text:      strings.Join(lines[first:last], "\n"),
+			})
+		}
+		if end >= len(lines) {
+			break
+		}
+
+		// Step back to share a few lines with the next chunk, always making progress
+		next := end - chunkOverlapLines
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
+
+// collectDocuments resolves the requested paths inside the allowed roots and returns
+// the documents they contain. Directories are walked recursively, skipping hidden
+// entries and files that are not text documents.
+func collectDocuments(roots []string, paths []string) (files []string, dirs []string, err error) {
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		resolved, err := resolveAllowedPath(roots, path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !info.IsDir() {
+			if !seen[resolved] {
+				seen[resolved] = true
+				files = append(files, resolved)
+			}
+			continue
+		}
+
+		dirs = append(dirs, resolved)
+		err = filepath.WalkDir(resolved, func(walked string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if walked != resolved && strings.HasPrefix(entry.Name(), ".") {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if entry.IsDir() || !documentExtensions[strings.ToLower(filepath.Ext(walked))] {
+				return nil
+			}
+
+			// Symbolic links are resolved again so they cannot leave the roots
+			file, err := resolveAllowedPath(roots, walked)
+			if err != nil {
+				return nil
+			}
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to walk %s: %w", path, err)
+		}
+	}
+
+	sort.Strings(files)
+	return files, dirs, nil
+}
+
+// readDocument reads a text document and returns its content and hash
+func readDocument(path string) (string, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", "", err
+	}
+	if info.Size() > maxDocumentBytes {
+		return "", "", fmt.Errorf("file exceeds %d bytes", maxDocumentBytes)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", "", fmt.Errorf("file is not a text document")
+	}
+
+	sum := sha256.Sum256(data)
+	return string(data), hex.EncodeToString(sum[:]), nil
+}
+
+// DocumentMatch is a chunk returned by a search, with its similarity to the query
+type DocumentMatch struct {
+	Path      string  `json:"path" jsonschema:"path of the file the chunk comes from"`
+	StartLine int     `json:"start_line" jsonschema:"first line of the chunk"`
+	EndLine   int     `json:"end_line" jsonschema:"last line of the chunk"`
+	Score     float64 `json:"score" jsonschema:"cosine similarity of the chunk to the query"`
+	Text      string  `json:"text" jsonschema:"content of the chunk"`
+}
+
+// citation formats the location of a match as path:start-end
+func (m DocumentMatch) citation() string {
+	return fmt.Sprintf("%s:%d-%d", m.Path, m.StartLine, m.EndLine)
+}
+
+// search returns the topK chunks of the index most similar to the query embedding
+func (i *DocumentIndex) search(query []float32, topK int) []DocumentMatch {
+	var matches []DocumentMatch
+	for path, file := range i.Files {
+		for _, chunk := range file.Chunks {
+			matches = append(matches, DocumentMatch{
+				Path:      path,
+				StartLine: chunk.StartLine,
+				EndLine:   chunk.EndLine,
+				Score:     cosineSimilarity(chunk.Embedding, query),
+				Text:      chunk.Text,
+			})
+		}
+	}
+
+	sort.Slice(matches, func(a, b int) bool {
+		if matches[a].Score != matches[b].Score {
+			return matches[a].Score > matches[b].Score
+		}
+		if matches[a].Path != matches[b].Path {
+			return matches[a].Path < matches[b].Path
+		}
+		return matches[a].StartLine < matches[b].StartLine
+	})
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return matches
+}
+
+// documentsPrompt builds the system prompt that gives the model the retrieved chunks
+func documentsPrompt(matches []DocumentMatch) string {
+	var b strings.Builder
+	b.WriteString("Answer the question using only the numbered excerpts below. ")
+	b.WriteString("Cite the excerpts you use with their number in brackets, such as [1]. ")
+	b.WriteString("If the excerpts do not contain the answer, say so.\n")
+	for n, match := range matches {
+		fmt.Fprintf(&b, "\n[%d] %s\n%s\n", n+1, match.citation(), match.Text)
+	}
+	return b.String()
+}
+
+// IndexDocumentsInput represents the input for the index-documents tool
+type IndexDocumentsInput struct {
+	Paths []string `json:"paths" jsonschema:"files or directories inside the allowed roots to index; directories are searched recursively for text and markdown files"`
+	Index string   `json:"index,omitempty" jsonschema:"name of the index to update (optional, default \"default\")"`
+	Model string   `json:"model,omitempty" jsonschema:"embedding model, defaults to the embed model; an existing index keeps the model it was built with (optional)"`
+	Reset bool     `json:"reset,omitempty" jsonschema:"discard the existing index before indexing (optional)"`
+}
+
+// IndexDocumentsOutput represents the output from the index-documents tool
+type IndexDocumentsOutput struct {
+	Index        string   `json:"index" jsonschema:"name of the updated index"`
+	Model        string   `json:"model" jsonschema:"embedding model of the index"`
+	Indexed      []string `json:"indexed,omitempty" jsonschema:"files that were added or re-indexed"`
+	Unchanged    int      `json:"unchanged" jsonschema:"number of files skipped because they did not change"`
+	Removed      []string `json:"removed,omitempty" jsonschema:"files dropped from the index because they no longer exist"`
+	Skipped      []string `json:"skipped,omitempty" jsonschema:"files that could not be indexed, with the reason"`
+	Files        int      `json:"files" jsonschema:"total number of files in the index"`
+	Chunks       int      `json:"chunks" jsonschema:"total number of chunks in the index"`
+	ChunksAdded  int      `json:"chunks_added" jsonschema:"number of chunks embedded by this call"`
+	PromptTokens int      `json:"prompt_tokens,omitempty" jsonschema:"number of tokens embedded by this call"`
+}
+
+// SearchDocumentsInput represents the input for the search-documents tool
+type SearchDocumentsInput struct {
+	Query string `json:"query" jsonschema:"the text to search for"`
+	Index string `json:"index,omitempty" jsonschema:"name of the index to search (optional, default \"default\")"`
+	TopK  int    `json:"top_k,omitempty" jsonschema:"number of chunks to return (optional, default 5)"`
+}
+
+// SearchDocumentsOutput represents the output from the search-documents tool
+type SearchDocumentsOutput struct {
+	Matches []DocumentMatch `json:"matches" jsonschema:"the most similar chunks, best first"`
+}
+
+// AskDocumentsInput represents the input for the ask-documents tool
+type AskDocumentsInput struct {
+	Question    string   `json:"question" jsonschema:"the question to answer from the indexed documents"`
+	Index       string   `json:"index,omitempty" jsonschema:"name of the index to search (optional, default \"default\")"`
+	TopK        int      `json:"top_k,omitempty" jsonschema:"number of chunks given to the model (optional, default 5)"`
+	Model       string   `json:"model,omitempty" jsonschema:"the Ollama model that answers, defaults to the chat model (optional)"`
+	Temperature *float32 `json:"temperature,omitempty" jsonschema:"controls randomness (0.0 to 1.0, optional)"`
+	ContextSize *int     `json:"context_size,omitempty" jsonschema:"maximum context size in tokens (optional)"`
+}
+
+// AskDocumentsOutput represents the output from the ask-documents tool
+type AskDocumentsOutput struct {
+	Model           string          `json:"model,omitempty" jsonschema:"the model that answered"`
+	Answer          string          `json:"answer" jsonschema:"the answer of the model, citing excerpts by number"`
+	Citations       []DocumentMatch `json:"citations" jsonschema:"the excerpts given to the model; citation [n] refers to the n-th entry"`
+	Usage           *Usage          `json:"usage,omitempty" jsonschema:"token counts, speed and timings of the generation"`
+	Truncated       bool            `json:"truncated,omitempty" jsonschema:"true when the answer is incomplete"`
+	TruncatedReason string          `json:"truncated_reason,omitempty" jsonschema:"why the answer is incomplete: timeout, cancelled or length"`
+	Cached          bool            `json:"cached,omitempty" jsonschema:"true when the answer was served from the response cache"`
+}
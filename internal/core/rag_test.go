@@ -0,0 +1,196 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Document retrieval", func() {
+	var (
+		ollama  *fakeOllama
+		root    string
+		docs    string
+		factory *core.HandlerFactory
+	)
+
+	writeDoc := func(name, content string) string {
+		path := filepath.Join(docs, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+		return path
+	}
+
+	index := func(input core.IndexDocumentsInput) core.IndexDocumentsOutput {
+		_, output, err := factory.IndexDocumentsHandler()(context.Background(), nil, input)
+		Expect(err).NotTo(HaveOccurred())
+		return output
+	}
+
+	BeforeEach(func() {
+		var err error
+		root, err = filepath.EvalSymlinks(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		docs = filepath.Join(root, "docs")
+
+		ollama = newFakeOllama()
+		ollama.embed = func(text string) []float32 {
+			text = strings.ToLower(text)
+			return []float32{float32(strings.Count(text, "ollama")), float32(strings.Count(text, "gopher")), 0.1}
+		}
+		factory = core.NewHandlerFactory(core.NewServer(&core.Config{
+			Client:       ollama.Client(),
+			ContextSize:  32000,
+			ChatModel:    "test-chat-model",
+			EmbedModel:   "test-embed-model",
+			KeepAlive:    "1m",
+			AllowedRoots: []string{root},
+			IndexDir:     filepath.Join(root, "indexes"),
+			Retry:        core.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		}))
+
+		writeDoc("ollama.md", "# Ollama\n\nOllama runs models locally.\n")
+		writeDoc("guide/gopher.txt", "The gopher is the Go mascot.\n")
+		writeDoc("image.png", "not a document")
+		writeDoc(".drafts/secret.md", "Ollama draft")
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should index the text and markdown files of a directory", func() {
+		output := index(core.IndexDocumentsInput{Paths: []string{"docs"}})
+		Expect(output.Index).To(Equal("default"))
+		Expect(output.Model).To(Equal("test-embed-model"))
+		Expect(output.Indexed).To(ConsistOf(filepath.Join(docs, "ollama.md"), filepath.Join(docs, "guide", "gopher.txt")))
+		Expect(output.Files).To(Equal(2))
+		Expect(output.Chunks).To(Equal(2))
+		Expect(filepath.Join(root, "indexes", "default.json")).To(BeAnExistingFile())
+	})
+
+	It("should only re-embed files that changed and drop deleted files", func() {
+		index(core.IndexDocumentsInput{Paths: []string{"docs"}})
+		requests := len(ollama.EmbedRequests())
+
+		output := index(core.IndexDocumentsInput{Paths: []string{"docs"}})
+		Expect(output.Unchanged).To(Equal(2))
+		Expect(output.ChunksAdded).To(BeZero())
+		Expect(ollama.EmbedRequests()).To(HaveLen(requests))
+
+		writeDoc("ollama.md", "Ollama serves models over HTTP.\n")
+		Expect(os.Remove(filepath.Join(docs, "guide", "gopher.txt"))).To(Succeed())
+
+		output = index(core.IndexDocumentsInput{Paths: []string{"docs"}})
+		Expect(output.Indexed).To(Equal([]string{filepath.Join(docs, "ollama.md")}))
+		Expect(output.Removed).To(Equal([]string{filepath.Join(docs, "guide", "gopher.txt")}))
+		Expect(output.Files).To(Equal(1))
+	})
+
+	It("should retry embedding requests failing with a transient error", func() {
+		ollama.failNext("/api/embed", http.StatusServiceUnavailable)
+
+		output := index(core.IndexDocumentsInput{Paths: []string{"docs"}})
+		Expect(output.Files).To(Equal(2))
+	})
+
+	It("should keep the files embedded before a failure", func() {
+		embed := ollama.embed
+		ollama.embed = func(text string) []float32 {
+			// Fail the request of the next file
+			if strings.Contains(text, "gopher") {
+				ollama.failNext("/api/embed", http.StatusBadRequest)
+			}
+			return embed(text)
+		}
+
+		_, _, err := factory.IndexDocumentsHandler()(context.Background(), nil, core.IndexDocumentsInput{Paths: []string{"docs"}})
+		Expect(err).To(MatchError(ContainSubstring("failed to embed " + filepath.Join(docs, "ollama.md"))))
+		ollama.embed = embed
+
+		_, search, err := factory.SearchDocumentsHandler()(context.Background(), nil, core.SearchDocumentsInput{Query: "gopher"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(search.Matches).To(HaveLen(1))
+
+		output := index(core.IndexDocumentsInput{Paths: []string{"docs"}})
+		Expect(output.Unchanged).To(Equal(1))
+		Expect(output.Indexed).To(Equal([]string{filepath.Join(docs, "ollama.md")}))
+	})
+
+	It("should split long documents into overlapping line ranges", func() {
+		var b strings.Builder
+		for i := 1; i <= 100; i++ {
+			fmt.Fprintf(&b, "line %d about ollama\n", i)
+		}
+		path := writeDoc("long.md", b.String())
+
+		output := index(core.IndexDocumentsInput{Paths: []string{path}})
+		Expect(output.Chunks).To(Equal(3))
+
+		_, search, err := factory.SearchDocumentsHandler()(context.Background(), nil, core.SearchDocumentsInput{Query: "ollama", TopK: 10})
+		Expect(err).NotTo(HaveOccurred())
+		var ranges []string
+		for _, match := range search.Matches {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", match.StartLine, match.EndLine))
+		}
+		Expect(ranges).To(ConsistOf("1-40", "36-75", "71-100"))
+	})
+
+	It("should return the chunks closest to the query", func() {
+		index(core.IndexDocumentsInput{Paths: []string{"docs"}})
+
+		_, output, err := factory.SearchDocumentsHandler()(context.Background(), nil, core.SearchDocumentsInput{Query: "what is a gopher", TopK: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Matches).To(HaveLen(1))
+		Expect(output.Matches[0].Path).To(Equal(filepath.Join(docs, "guide", "gopher.txt")))
+		Expect(output.Matches[0].StartLine).To(Equal(1))
+		Expect(output.Matches[0].EndLine).To(Equal(1))
+		Expect(output.Matches[0].Text).To(Equal("The gopher is the Go mascot."))
+	})
+
+	It("should answer from the retrieved chunks with citations", func() {
+		index(core.IndexDocumentsInput{Paths: []string{"docs"}})
+
+		_, output, err := factory.AskDocumentsHandler()(context.Background(), nil, core.AskDocumentsInput{Question: "How does Ollama run models?", TopK: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Answer).To(Equal("ok"))
+		Expect(output.Citations).To(HaveLen(1))
+		Expect(output.Citations[0].Path).To(Equal(filepath.Join(docs, "ollama.md")))
+
+		messages := ollama.ChatRequests()[0].Messages
+		Expect(messages[0].Role).To(Equal("system"))
+		Expect(messages[0].Content).To(ContainSubstring("[1] " + filepath.Join(docs, "ollama.md") + ":1-3"))
+		Expect(messages[0].Content).To(ContainSubstring("Ollama runs models locally."))
+		Expect(messages[1].Content).To(Equal("How does Ollama run models?"))
+	})
+
+	It("should keep the embedding model of an existing index", func() {
+		index(core.IndexDocumentsInput{Paths: []string{"docs"}})
+
+		_, _, err := factory.IndexDocumentsHandler()(context.Background(), nil, core.IndexDocumentsInput{Paths: []string{"docs"}, Model: "other-embed-model"})
+		Expect(err).To(MatchError(ContainSubstring("reset")))
+
+		output := index(core.IndexDocumentsInput{Paths: []string{"docs"}, Model: "other-embed-model", Reset: true})
+		Expect(output.Model).To(Equal("other-embed-model"))
+		Expect(output.Indexed).To(HaveLen(2))
+	})
+
+	It("should refuse paths outside the allowed roots", func() {
+		_, _, err := factory.IndexDocumentsHandler()(context.Background(), nil, core.IndexDocumentsInput{Paths: []string{os.TempDir()}})
+		Expect(err).To(MatchError(ContainSubstring("outside the allowed roots")))
+	})
+
+	It("should report an index that was never built", func() {
+		_, _, err := factory.SearchDocumentsHandler()(context.Background(), nil, core.SearchDocumentsInput{Query: "ollama", Index: "missing"})
+		Expect(err).To(MatchError(ContainSubstring("index not found")))
+	})
+})
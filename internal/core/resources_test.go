@@ -0,0 +1,99 @@
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Model resources", func() {
+	var (
+		ollama  *fakeOllama
+		session *mcp.ClientSession
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.models["qwen3-coder:30b"] = &api.ShowResponse{
+			Modelfile:  "FROM qwen3-coder:30b\nPARAMETER temperature 0.7\n",
+			Parameters: "temperature 0.7",
+		}
+		ollama.models["llama3.2"] = &api.ShowResponse{Modelfile: "FROM llama3.2\n"}
+
+		handlers := core.NewHandlerFactory(core.NewServer(&core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32000,
+			ChatModel:   "llama3.2",
+		}))
+
+		server := mcp.NewServer(&mcp.Implementation{Name: "test-server", Version: "test"}, nil)
+		server.AddResource(&mcp.Resource{URI: core.ModelsResourceURI, Name: "models", MIMEType: "application/json"}, handlers.ModelsResourceHandler())
+		server.AddResourceTemplate(&mcp.ResourceTemplate{URITemplate: core.ModelResourceTemplate, Name: "model", MIMEType: "application/json"}, handlers.ModelResourceHandler())
+		server.AddResourceTemplate(&mcp.ResourceTemplate{URITemplate: core.ModelfileResourceTemplate, Name: "modelfile", MIMEType: "text/plain"}, handlers.ModelResourceHandler())
+
+		session = connectClient(server, nil)
+	})
+
+	AfterEach(func() {
+		_ = session.Close()
+		ollama.Close()
+	})
+
+	read := func(uri string) *mcp.ResourceContents {
+		result, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: uri})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Contents).To(HaveLen(1))
+		return result.Contents[0]
+	}
+
+	It("should list the models with the URI of each model", func() {
+		contents := read("ollama://models")
+		Expect(contents.MIMEType).To(Equal("application/json"))
+
+		var output core.ListModelsOutput
+		Expect(json.Unmarshal([]byte(contents.Text), &output)).To(Succeed())
+		Expect(output.Models).To(HaveLen(2))
+		Expect(output.Models[0].Name).To(Equal("llama3.2"))
+		Expect(output.Models[0].URI).To(Equal("ollama://models/llama3.2"))
+		Expect(output.Models[1].URI).To(Equal("ollama://models/qwen3-coder%3A30b"))
+	})
+
+	It("should read the details of a model", func() {
+		contents := read(core.ModelResourceURI("qwen3-coder:30b"))
+		Expect(contents.MIMEType).To(Equal("application/json"))
+
+		var output core.ModelInfoOutput
+		Expect(json.Unmarshal([]byte(contents.Text), &output)).To(Succeed())
+		Expect(output.Name).To(Equal("qwen3-coder:30b"))
+		Expect(output.Parameters).To(Equal("temperature 0.7"))
+	})
+
+	It("should read the raw Modelfile of a model", func() {
+		contents := read("ollama://models/qwen3-coder%3A30b/modelfile")
+		Expect(contents.MIMEType).To(Equal("text/plain"))
+		Expect(contents.Text).To(Equal("FROM qwen3-coder:30b\nPARAMETER temperature 0.7\n"))
+	})
+
+	It("should advertise the resource templates", func() {
+		result, err := session.ListResourceTemplates(context.Background(), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var templates []string
+		for _, template := range result.ResourceTemplates {
+			templates = append(templates, template.URITemplate)
+		}
+		Expect(templates).To(ConsistOf("ollama://models/{name}", "ollama://models/{name}/modelfile"))
+	})
+
+	It("should report a missing model as a missing resource", func() {
+		_, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: "ollama://models/missing"})
+		Expect(err).To(MatchError(ContainSubstring("not found")))
+	})
+})
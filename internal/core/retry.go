@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Default retry policy
+const (
+	DefaultRetryAttempts   = 3
+	DefaultRetryBackoff    = 500 * time.Millisecond
+	DefaultRetryMaxBackoff = 10 * time.Second
+)
+
+// RetryPolicy controls how requests failing with transient Ollama errors are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, 1 or less disabling retries
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled after each retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    DefaultRetryAttempts,
+		InitialBackoff: DefaultRetryBackoff,
+		MaxBackoff:     DefaultRetryMaxBackoff,
+	}
+}
+
+// permanentError marks an error that must not be retried whatever its cause
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// noRetry stops the retries of a request, for example once part of a streamed
+// response was already received
+func noRetry(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// do calls fn until it succeeds, fails with an error that is not retryable, or the
+// attempts run out. Attempts are separated by an exponential backoff with jitter.
+func (p RetryPolicy) do(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if err == nil || attempt >= p.MaxAttempts || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-time.After(p.backoff(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// backoff returns the wait after the given attempt: the initial backoff doubled for
+// every previous retry, capped by the maximum, and randomized down to half its value
+// so that clients failing together do not retry together
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	if delay <= 0 {
+		delay = DefaultRetryBackoff
+	}
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// isRetryable reports whether an Ollama error is transient: a network failure, a server
+// error such as a model failing to load, or a busy server. Missing models, models too large
+// for the available memory, invalid requests and timeouts are fatal.
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, errFirstTokenTimeout) || errors.Is(err, errIdleTimeout) {
+		return false
+	}
+
+	// A model that does not fit in memory will not fit any better on the next attempt
+	if isMemoryError(err) {
+		return false
+	}
+
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,107 @@
+package core_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Retries", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.models["test-chat-model"] = &api.ShowResponse{License: "MIT"}
+		config = &core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32000,
+			ChatModel:   "test-chat-model",
+			CodeModel:   "test-code-model",
+			KeepAlive:   "1m",
+			Retry: core.RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     5 * time.Millisecond,
+			},
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should retry a chat when the server is busy or the connection drops", func() {
+		ollama.failNext("/api/chat", http.StatusServiceUnavailable, 0)
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Response).To(Equal("ok"))
+	})
+
+	It("should retry code requests when the model fails to load", func() {
+		ollama.failNext("/api/chat", http.StatusInternalServerError)
+
+		_, output, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{Message: "hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Response).To(Equal("ok"))
+	})
+
+	It("should give up once the attempts run out", func() {
+		ollama.failNext("/api/chat", http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello"})
+		Expect(err).To(MatchError(ContainSubstring("Service Unavailable")))
+	})
+
+	It("should not retry a missing model", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse { return nil }
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello"})
+		Expect(err).To(MatchError(ContainSubstring("not found")))
+		Expect(ollama.ChatRequests()).To(HaveLen(1))
+	})
+
+	It("should not retry an invalid request", func() {
+		ollama.failNext("/api/chat", http.StatusBadRequest, http.StatusBadRequest)
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello"})
+		Expect(err).To(MatchError(ContainSubstring("Bad Request")))
+
+		_, _, err = factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello"})
+		Expect(err).To(MatchError(ContainSubstring("Bad Request")))
+		Expect(ollama.ChatRequests()).To(BeEmpty())
+	})
+
+	It("should retry listing and showing models", func() {
+		ollama.failNext("/api/tags", http.StatusBadGateway)
+		ollama.failNext("/api/show", 0)
+
+		_, models, err := factory.ListModelsHandler()(context.Background(), nil, core.ListModelsInput{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(models.Models).To(HaveLen(1))
+
+		_, info, err := factory.ModelInfoHandler()(context.Background(), nil, core.ModelInfoInput{Name: "test-chat-model"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.License).To(Equal("MIT"))
+	})
+
+	It("should not retry when retries are disabled", func() {
+		config.Retry.MaxAttempts = 1
+		ollama.failNext("/api/chat", http.StatusServiceUnavailable)
+
+		_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hello"})
+		Expect(err).To(HaveOccurred())
+	})
+})
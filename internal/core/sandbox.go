@@ -0,0 +1,66 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveAllowedPath resolves a path and checks that it stays inside one of the allowed roots.
+// Relative paths are resolved against the first root. Symbolic links are followed
+// before the check so they cannot be used to escape the roots.
+func resolveAllowedPath(roots []string, path string) (string, error) {
+	if len(roots) == 0 {
+		return "", fmt.Errorf("file access is disabled: no allowed roots configured")
+	}
+	if path == "" {
+		return "", fmt.Errorf("path cannot be empty")
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(roots[0], path)
+	}
+	resolved, err := evalPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	for _, root := range roots {
+		resolvedRoot, err := evalPath(root)
+		if err != nil {
+			continue
+		}
+		if isWithin(resolvedRoot, resolved) {
+			return resolved, nil
+		}
+	}
+
+	return "", fmt.Errorf("path is outside the allowed roots: %s", path)
+}
+
+// evalPath returns the absolute, symlink-free form of a path
+func evalPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path %s: %w", path, err)
+	}
+
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("path does not exist: %s", path)
+		}
+		return "", fmt.Errorf("invalid path %s: %w", path, err)
+	}
+	return resolved, nil
+}
+
+// isWithin reports whether path is root or one of its descendants
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,285 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	// DefaultMaxSessions bounds the number of sessions kept in memory
+	DefaultMaxSessions = 100
+
+	// DefaultSessionTTL is how long an idle session is kept
+	DefaultSessionTTL = 24 * time.Hour
+)
+
+// Session holds the message history of a multi-turn conversation
+type Session struct {
+	ID           string
+	SystemPrompt string
+	Messages     []api.Message
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// SessionStore keeps conversation sessions in memory. Sessions idle for longer than the
+// TTL expire, and the least recently updated session is evicted when the store is full.
+type SessionStore struct {
+	mu          sync.RWMutex
+	sessions    map[string]*Session
+	maxSessions int
+	ttl         time.Duration
+}
+
+// NewSessionStore creates an empty session store
+func NewSessionStore(maxSessions int, ttl time.Duration) *SessionStore {
+	if maxSessions <= 0 {
+		maxSessions = DefaultMaxSessions
+	}
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	return &SessionStore{
+		sessions:    make(map[string]*Session),
+		maxSessions: maxSessions,
+		ttl:         ttl,
+	}
+}
+
+// Create starts a new session with an optional system prompt
+func (s *SessionStore) Create(systemPrompt string) (*Session, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:           id,
+		SystemPrompt: systemPrompt,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if systemPrompt != "" {
+		session.Messages = []api.Message{{Role: "system", Content: systemPrompt}}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evict(now)
+	s.sessions[id] = session
+
+	return session.clone(), nil
+}
+
+// evict removes the expired sessions, then the least recently updated ones until
+// there is room for a new session. The caller must hold the write lock.
+func (s *SessionStore) evict(now time.Time) {
+	for id, session := range s.sessions {
+		if now.Sub(session.UpdatedAt) > s.ttl {
+			delete(s.sessions, id)
+		}
+	}
+
+	for len(s.sessions) >= s.maxSessions {
+		var oldest *Session
+		for _, session := range s.sessions {
+			if oldest == nil || session.UpdatedAt.Before(oldest.UpdatedAt) {
+				oldest = session
+			}
+		}
+		delete(s.sessions, oldest.ID)
+	}
+}
+
+// lookup returns the session with the given ID unless it has expired. The caller must hold the lock.
+func (s *SessionStore) lookup(id string) (*Session, error) {
+	session, ok := s.sessions[id]
+	if !ok || time.Since(session.UpdatedAt) > s.ttl {
+		return nil, fmt.Errorf("session not found: %s", id)
+	}
+	return session, nil
+}
+
+// Get returns a copy of the session with the given ID
+func (s *SessionStore) Get(id string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, err := s.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	return session.clone(), nil
+}
+
+// List returns copies of all sessions that have not expired, oldest first
+func (s *SessionStore) List() []*Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := make([]*Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		if time.Since(session.UpdatedAt) <= s.ttl {
+			sessions = append(sessions, session.clone())
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions
+}
+
+// Append adds messages to the history of a session
+func (s *SessionStore) Append(id string, messages ...api.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+	session.Messages = append(session.Messages, messages...)
+	session.UpdatedAt = time.Now()
+	return nil
+}
+
+// Reset clears the history of a session, keeping its initial system prompt
+func (s *SessionStore) Reset(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+	session.Messages = nil
+	if session.SystemPrompt != "" {
+		session.Messages = []api.Message{{Role: "system", Content: session.SystemPrompt}}
+	}
+	session.UpdatedAt = time.Now()
+	return nil
+}
+
+// Delete removes a session
+func (s *SessionStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.lookup(id); err != nil {
+		return err
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+// clone returns a copy of the session that is safe to use outside the store lock
+func (s *Session) clone() *Session {
+	c := *s
+	c.Messages = append([]api.Message(nil), s.Messages...)
+	return &c
+}
+
+// lastSystemPrompt returns the content of the most recent system message
+func (s *Session) lastSystemPrompt() string {
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if s.Messages[i].Role == "system" {
+			return s.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// newSessionID generates a random session identifier
+func newSessionID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SessionMessage represents a single message in a session history
+type SessionMessage struct {
+	Role    string `json:"role" jsonschema:"role of the message author (system, user or assistant)"`
+	Content string `json:"content" jsonschema:"content of the message"`
+}
+
+// SessionSummary describes a session without its history
+type SessionSummary struct {
+	ID           string `json:"id" jsonschema:"identifier of the session"`
+	SystemPrompt string `json:"system_prompt,omitempty" jsonschema:"initial system prompt of the session"`
+	MessageCount int    `json:"message_count" jsonschema:"number of messages in the session history"`
+	CreatedAt    string `json:"created_at" jsonschema:"timestamp when the session was created"`
+	UpdatedAt    string `json:"updated_at" jsonschema:"timestamp when the session was last updated"`
+}
+
+// CreateSessionInput represents the input for the create-session tool
+type CreateSessionInput struct {
+	SystemPrompt string `json:"system_prompt,omitempty" jsonschema:"system prompt to start the session with (optional)"`
+}
+
+// CreateSessionOutput represents the output from the create-session tool
+type CreateSessionOutput struct {
+	Session SessionSummary `json:"session" jsonschema:"the created session"`
+}
+
+// ListSessionsInput represents the input for the list-sessions tool
+type ListSessionsInput struct {
+	// No input parameters needed for listing sessions
+}
+
+// ListSessionsOutput represents the output from the list-sessions tool
+type ListSessionsOutput struct {
+	Sessions []SessionSummary `json:"sessions" jsonschema:"list of active sessions"`
+}
+
+// SessionInfoInput represents the input for the session-info tool
+type SessionInfoInput struct {
+	SessionID string `json:"session_id" jsonschema:"identifier of the session to inspect"`
+}
+
+// SessionInfoOutput represents the output from the session-info tool
+type SessionInfoOutput struct {
+	Session  SessionSummary   `json:"session" jsonschema:"the session"`
+	Messages []SessionMessage `json:"messages" jsonschema:"message history of the session"`
+}
+
+// ResetSessionInput represents the input for the reset-session tool
+type ResetSessionInput struct {
+	SessionID string `json:"session_id" jsonschema:"identifier of the session to reset"`
+}
+
+// ResetSessionOutput represents the output from the reset-session tool
+type ResetSessionOutput struct {
+	Status  string `json:"status" jsonschema:"status of the reset operation"`
+	Message string `json:"message" jsonschema:"message from the reset operation"`
+}
+
+// DeleteSessionInput represents the input for the delete-session tool
+type DeleteSessionInput struct {
+	SessionID string `json:"session_id" jsonschema:"identifier of the session to delete"`
+}
+
+// DeleteSessionOutput represents the output from the delete-session tool
+type DeleteSessionOutput struct {
+	Status  string `json:"status" jsonschema:"status of the delete operation"`
+	Message string `json:"message" jsonschema:"message from the delete operation"`
+}
+
+// summary converts a session to its tool output representation
+func (s *Session) summary() SessionSummary {
+	return SessionSummary{
+		ID:           s.ID,
+		SystemPrompt: s.SystemPrompt,
+		MessageCount: len(s.Messages),
+		CreatedAt:    s.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    s.UpdatedAt.Format(time.RFC3339),
+	}
+}
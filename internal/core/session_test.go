@@ -0,0 +1,164 @@
+package core_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Sessions", func() {
+	Describe("SessionStore", func() {
+		var store *core.SessionStore
+
+		BeforeEach(func() {
+			store = core.NewSessionStore(0, 0)
+		})
+
+		It("should create sessions seeded with the system prompt", func() {
+			session, err := store.Create("be brief")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(session.ID).NotTo(BeEmpty())
+			Expect(session.Messages).To(HaveLen(1))
+			Expect(session.Messages[0].Role).To(Equal("system"))
+		})
+
+		It("should return an error for unknown sessions", func() {
+			_, err := store.Get("missing")
+			Expect(err).To(HaveOccurred())
+			Expect(store.Reset("missing")).To(HaveOccurred())
+		})
+
+		It("should keep the system prompt when reset", func() {
+			session, err := store.Create("be brief")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.Append(session.ID, userMessage("hi"))).To(Succeed())
+			Expect(store.Reset(session.ID)).To(Succeed())
+
+			session, err = store.Get(session.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(session.Messages).To(HaveLen(1))
+			Expect(session.Messages[0].Content).To(Equal("be brief"))
+		})
+
+		It("should delete sessions", func() {
+			session, err := store.Create("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.Delete(session.ID)).To(Succeed())
+
+			_, err = store.Get(session.ID)
+			Expect(err).To(HaveOccurred())
+			Expect(store.Delete(session.ID)).To(HaveOccurred())
+		})
+
+		It("should evict the least recently updated session when full", func() {
+			store = core.NewSessionStore(2, time.Hour)
+			first, _ := store.Create("")
+			second, _ := store.Create("")
+			Expect(store.Append(first.ID, userMessage("still here"))).To(Succeed())
+
+			third, err := store.Create("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.List()).To(HaveLen(2))
+			_, err = store.Get(second.ID)
+			Expect(err).To(HaveOccurred())
+			_, err = store.Get(first.ID)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = store.Get(third.ID)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should expire idle sessions", func() {
+			store = core.NewSessionStore(10, 20*time.Millisecond)
+			session, err := store.Create("")
+			Expect(err).NotTo(HaveOccurred())
+
+			time.Sleep(30 * time.Millisecond)
+			_, err = store.Get(session.ID)
+			Expect(err).To(MatchError(ContainSubstring("session not found")))
+			Expect(store.Append(session.ID, userMessage("hi"))).To(HaveOccurred())
+			Expect(store.List()).To(BeEmpty())
+		})
+
+		It("should list sessions in creation order", func() {
+			first, _ := store.Create("")
+			second, _ := store.Create("")
+			sessions := store.List()
+			Expect(sessions).To(HaveLen(2))
+			Expect(sessions[0].ID).To(Equal(first.ID))
+			Expect(sessions[1].ID).To(Equal(second.ID))
+		})
+	})
+
+	Describe("Chat with a session", func() {
+		var (
+			ollama  *fakeOllama
+			factory *core.HandlerFactory
+		)
+
+		BeforeEach(func() {
+			ollama = newFakeOllama()
+			factory = core.NewHandlerFactory(core.NewServer(&core.Config{
+				Client:      ollama.Client(),
+				ContextSize: 32000,
+				CodeModel:   "test-code-model",
+				ChatModel:   "test-chat-model",
+				KeepAlive:   "1m",
+			}))
+		})
+
+		AfterEach(func() {
+			ollama.Close()
+		})
+
+		It("should replay the history on each call", func() {
+			_, created, err := factory.CreateSessionHandler()(context.Background(), nil, core.CreateSessionInput{})
+			Expect(err).NotTo(HaveOccurred())
+			sessionID := created.Session.ID
+
+			chat := factory.ChatHandler()
+			_, output, err := chat(context.Background(), nil, core.ChatInput{Message: "first", SessionID: sessionID})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.SessionID).To(Equal(sessionID))
+
+			_, _, err = chat(context.Background(), nil, core.ChatInput{Message: "second", SessionID: sessionID})
+			Expect(err).NotTo(HaveOccurred())
+
+			requests := ollama.ChatRequests()
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[1].Messages).To(HaveLen(3))
+			Expect(requests[1].Messages[0].Content).To(Equal("first"))
+			Expect(requests[1].Messages[1].Role).To(Equal("assistant"))
+			Expect(requests[1].Messages[2].Content).To(Equal("second"))
+
+			_, info, err := factory.SessionInfoHandler()(context.Background(), nil, core.SessionInfoInput{SessionID: sessionID})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Messages).To(HaveLen(4))
+		})
+
+		It("should record the code tool system prompt only once", func() {
+			_, created, err := factory.CreateSessionHandler()(context.Background(), nil, core.CreateSessionInput{})
+			Expect(err).NotTo(HaveOccurred())
+
+			code := factory.CodeHandler()
+			for _, message := range []string{"one", "two"} {
+				_, _, err := code(context.Background(), nil, core.CodeInput{Message: message, SessionID: created.Session.ID})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			_, info, err := factory.SessionInfoHandler()(context.Background(), nil, core.SessionInfoInput{SessionID: created.Session.ID})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Messages).To(HaveLen(5))
+			Expect(info.Messages[0].Role).To(Equal("system"))
+		})
+
+		It("should fail for unknown sessions", func() {
+			_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hi", SessionID: "missing"})
+			Expect(err).To(HaveOccurred())
+			Expect(ollama.ChatRequests()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// thinkBlockPattern matches the inline reasoning blocks some models emit in their content
+var thinkBlockPattern = regexp.MustCompile(`(?s)<think>(.*?)</think>`)
+
+// parseThink converts the think input, a boolean or a "low", "medium" or "high" level,
+// into the value sent to Ollama
+func parseThink(think any) (*api.ThinkValue, error) {
+	switch t := think.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return &api.ThinkValue{Value: t}, nil
+	case string:
+		switch t {
+		case "low", "medium", "high":
+			return &api.ThinkValue{Value: t}, nil
+		case "true", "false":
+			return &api.ThinkValue{Value: t == "true"}, nil
+		}
+		return nil, fmt.Errorf("think must be a boolean or one of low, medium, high, got %q", t)
+	default:
+		return nil, fmt.Errorf("think must be a boolean or one of low, medium, high")
+	}
+}
+
+// splitThinking moves inline <think> blocks out of the content. An unterminated
+// block, as left by a truncated reply, runs to the end of the content, and a closing
+// tag without an opening one, as left by models whose template opens the block in
+// the prompt, ends a block that started with the content.
+func splitThinking(content string) (answer, thinking string) {
+	var blocks []string
+	if before, after, ok := strings.Cut(content, "</think>"); ok && !strings.Contains(before, "<think>") {
+		blocks = append(blocks, strings.TrimSpace(before))
+		content = after
+	}
+
+	answer = thinkBlockPattern.ReplaceAllStringFunc(content, func(block string) string {
+		blocks = append(blocks, strings.TrimSpace(thinkBlockPattern.FindStringSubmatch(block)[1]))
+		return ""
+	})
+
+	if before, after, ok := strings.Cut(answer, "<think>"); ok {
+		blocks = append(blocks, strings.TrimSpace(after))
+		answer = before
+	}
+
+	return strings.TrimSpace(answer), strings.Join(blocks, "\n\n")
+}
+
+// hasInlineThinking reports whether the content holds a <think> or </think> tag
+func hasInlineThinking(content string) bool {
+	return strings.Contains(content, "<think>") || strings.Contains(content, "</think>")
+}
+
+// maxHeldThinking bounds the content held back while waiting for the closing tag of
+// reasoning opened by the prompt template; longer content is taken for the answer
+const maxHeldThinking = 2048
+
+// thinkFilter removes inline <think> blocks from streamed content, so that progress
+// messages only carry the answer. Some models open the block in their prompt template,
+// so the start of the content is held back until a </think> shows it was reasoning, or
+// until a <think>, the end of the stream or maxHeldThinking bytes show it was not.
+// Tags split across chunks are held back until the next chunk tells them apart from
+// the answer.
+type thinkFilter struct {
+	decided bool
+	held    string
+	inside  bool
+	pending string
+}
+
+// visible returns the part of a streamed chunk outside of think blocks
+func (f *thinkFilter) visible(chunk string) string {
+	if !f.decided {
+		f.held += chunk
+		if before, after, ok := strings.Cut(f.held, "</think>"); ok && !strings.Contains(before, "<think>") {
+			chunk = after
+		} else if strings.Contains(f.held, "<think>") || len(f.held) > maxHeldThinking {
+			chunk = f.held
+		} else {
+			return ""
+		}
+		f.decided = true
+		f.held = ""
+	}
+
+	s := f.pending + chunk
+	f.pending = ""
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "<")
+		if i < 0 {
+			f.write(&b, s)
+			return b.String()
+		}
+		f.write(&b, s[:i])
+		s = s[i:]
+
+		switch {
+		case strings.HasPrefix(s, "<think>"):
+			f.inside = true
+			s = s[len("<think>"):]
+		case strings.HasPrefix(s, "</think>"):
+			f.inside = false
+			s = s[len("</think>"):]
+		case strings.HasPrefix("<think>", s) || strings.HasPrefix("</think>", s):
+			f.pending = s
+			return b.String()
+		default:
+			f.write(&b, s[:1])
+			s = s[1:]
+		}
+	}
+}
+
+// flush returns the content still held back once the stream has ended: text that
+// turned out not to be reasoning, and a partial tag that turned out to be text
+func (f *thinkFilter) flush() string {
+	var text string
+	if !f.decided {
+		f.decided = true
+		held := f.held
+		f.held = ""
+		text = f.visible(held)
+	}
+	if !f.inside {
+		text += f.pending
+	}
+	f.pending = ""
+	return text
+}
+
+// write appends text to the visible content unless it is inside a think block
+func (f *thinkFilter) write(b *strings.Builder, text string) {
+	if !f.inside {
+		b.WriteString(text)
+	}
+}
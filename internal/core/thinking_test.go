@@ -0,0 +1,99 @@
+package core_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Thinking", func() {
+	var (
+		ollama  *fakeOllama
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		factory = core.NewHandlerFactory(core.NewServer(&core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32000,
+			ChatModel:   "test-chat-model",
+			CodeModel:   "test-code-model",
+			KeepAlive:   "1m",
+		}))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should pass the think level and return the reasoning separately", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{
+				{Message: api.Message{Role: "assistant", Thinking: "Let me "}},
+				{Message: api.Message{Role: "assistant", Thinking: "think."}},
+				{Message: api.Message{Role: "assistant", Content: "42"}, Done: true},
+			}
+		}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hi", Think: "high"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Response).To(Equal("42"))
+		Expect(output.Thinking).To(Equal("Let me think."))
+		Expect(ollama.ChatRequests()[0].Think.String()).To(Equal("high"))
+	})
+
+	It("should strip inline think blocks from the content", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{{Message: api.Message{Role: "assistant", Content: "<think>\nhmm\n</think>\n\nfunc main() {}"}, Done: true}}
+		}
+
+		_, output, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{Message: "hi"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Response).To(Equal("func main() {}"))
+		Expect(output.Thinking).To(Equal("hmm"))
+		Expect(ollama.ChatRequests()[0].Think).To(BeNil())
+	})
+
+	It("should treat an unterminated think block as reasoning", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{{Message: api.Message{Role: "assistant", Content: "<think>still going"}, Done: true}}
+		}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hi"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Response).To(BeEmpty())
+		Expect(output.Thinking).To(Equal("still going"))
+	})
+
+	It("should treat the content before an unmatched closing tag as reasoning", func() {
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{{Message: api.Message{Role: "assistant", Content: "hmm\n</think>\n\n42"}, Done: true}}
+		}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hi"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Response).To(Equal("42"))
+		Expect(output.Thinking).To(Equal("hmm"))
+	})
+
+	DescribeTable("think validation",
+		func(think any, shouldBeValid bool) {
+			err := factory.ValidateChatInput(core.ChatInput{Message: "hi", Think: think})
+			if shouldBeValid {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("boolean", true, true),
+		Entry("level", "low", true),
+		Entry("unknown level", "extreme", false),
+		Entry("number", 3.0, false),
+	)
+})
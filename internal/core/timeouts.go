@@ -0,0 +1,196 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Default timeouts
+const (
+	DefaultGenerationTimeout = 10 * time.Minute
+	DefaultFirstTokenTimeout = 2 * time.Minute
+	DefaultIdleTimeout       = 30 * time.Second
+	DefaultModelsTimeout     = 10 * time.Second
+)
+
+var (
+	errFirstTokenTimeout = errors.New("model produced no output before the first-token timeout")
+	errIdleTimeout       = errors.New("model output stalled longer than the idle timeout")
+)
+
+// Timeouts holds the time limits applied to a tool call
+type Timeouts struct {
+	// Total bounds the whole tool call
+	Total time.Duration
+	// FirstToken bounds the wait for the first streamed token, which includes loading the model
+	FirstToken time.Duration
+	// Idle bounds the gap between two streamed tokens
+	Idle time.Duration
+}
+
+// defaultTimeouts returns the built-in timeouts of a tool
+func defaultTimeouts(toolName string) Timeouts {
+	switch toolName {
+	case "list-models", "model-info":
+		return Timeouts{Total: DefaultModelsTimeout}
+	default:
+		return Timeouts{
+			Total:      DefaultGenerationTimeout,
+			FirstToken: DefaultFirstTokenTimeout,
+			Idle:       DefaultIdleTimeout,
+		}
+	}
+}
+
+// GetTimeouts returns the timeouts for the specified tool. Values configured for
+// the tool take precedence over values configured for all tools ("*"), which take
+// precedence over the built-in defaults.
+func (c *Config) GetTimeouts(toolName string) Timeouts {
+	timeouts := defaultTimeouts(toolName)
+	for _, key := range []string{"*", toolName} {
+		configured, ok := c.Timeouts[key]
+		if !ok {
+			continue
+		}
+		if configured.Total > 0 {
+			timeouts.Total = configured.Total
+		}
+		if configured.FirstToken > 0 {
+			timeouts.FirstToken = configured.FirstToken
+		}
+		if configured.Idle > 0 {
+			timeouts.Idle = configured.Idle
+		}
+	}
+	return timeouts
+}
+
+// SetTimeouts parses per-tool total, first-token and idle timeouts. Each spec is either
+// a duration applying to all tools, such as "5m", or a list such as "chat=5m,code=10m".
+// Empty specs leave the corresponding timeouts unchanged.
+func (c *Config) SetTimeouts(total, firstToken, idle string) error {
+	specs := []struct {
+		spec string
+		set  func(*Timeouts, time.Duration)
+	}{
+		{total, func(t *Timeouts, d time.Duration) { t.Total = d }},
+		{firstToken, func(t *Timeouts, d time.Duration) { t.FirstToken = d }},
+		{idle, func(t *Timeouts, d time.Duration) { t.Idle = d }},
+	}
+
+	for _, s := range specs {
+		durations, err := ParseToolDurations(s.spec)
+		if err != nil {
+			return err
+		}
+
+		for tool, duration := range durations {
+			if c.Timeouts == nil {
+				c.Timeouts = make(map[string]Timeouts)
+			}
+			timeouts := c.Timeouts[tool]
+			s.set(&timeouts, duration)
+			c.Timeouts[tool] = timeouts
+		}
+	}
+	return nil
+}
+
+// ParseToolDurations parses a comma-separated list of tool=duration pairs.
+// An entry without a tool name is stored under "*".
+func ParseToolDurations(spec string) (map[string]time.Duration, error) {
+	durations := make(map[string]time.Duration)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		tool, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			tool, value = "*", entry
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration for %s: %w", tool, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("duration for %s must be positive", tool)
+		}
+		durations[strings.TrimSpace(tool)] = duration
+	}
+	return durations, nil
+}
+
+// watchdog cancels a streamed request that waits too long for its first token
+// or stalls between two tokens
+type watchdog struct {
+	timeouts Timeouts
+	cancel   context.CancelCauseFunc
+	timer    *time.Timer
+	started  bool
+}
+
+// startWatchdog returns a context that is cancelled when the first-token timeout expires
+func startWatchdog(ctx context.Context, timeouts Timeouts) (context.Context, *watchdog) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	w := &watchdog{timeouts: timeouts, cancel: cancel}
+	if timeouts.FirstToken > 0 {
+		w.timer = time.AfterFunc(timeouts.FirstToken, func() {
+			cancel(fmt.Errorf("%w (%s)", errFirstTokenTimeout, timeouts.FirstToken))
+		})
+	}
+	return ctx, w
+}
+
+// tick records that a token was received and restarts the idle timer
+func (w *watchdog) tick() {
+	if w.started {
+		if w.timer != nil {
+			w.timer.Reset(w.timeouts.Idle)
+		}
+		return
+	}
+
+	w.started = true
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.timeouts.Idle > 0 {
+		idle := w.timeouts.Idle
+		w.timer = time.AfterFunc(idle, func() {
+			w.cancel(fmt.Errorf("%w (%s)", errIdleTimeout, idle))
+		})
+	}
+}
+
+// stop releases the watchdog and returns the timeout that cancelled the request, if any
+func (w *watchdog) stop(ctx context.Context) error {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	cause := context.Cause(ctx)
+	w.cancel(nil)
+
+	if errors.Is(cause, errFirstTokenTimeout) || errors.Is(cause, errIdleTimeout) {
+		return cause
+	}
+	return nil
+}
+
+// interruptionReason reports why a generation was cut short: "cancelled" when the
+// caller cancelled the tool call, "timeout" when a deadline expired, or "" for other errors
+func interruptionReason(ctx context.Context, err error) string {
+	switch {
+	case ctx.Err() != nil:
+		return "cancelled"
+	case errors.Is(err, errFirstTokenTimeout), errors.Is(err, errIdleTimeout), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,183 @@
+package core_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Timeouts", func() {
+	Describe("Config.GetTimeouts", func() {
+		It("should use the built-in defaults", func() {
+			config := &core.Config{}
+			Expect(config.GetTimeouts("chat")).To(Equal(core.Timeouts{
+				Total:      core.DefaultGenerationTimeout,
+				FirstToken: core.DefaultFirstTokenTimeout,
+				Idle:       core.DefaultIdleTimeout,
+			}))
+			Expect(config.GetTimeouts("list-models").Total).To(Equal(core.DefaultModelsTimeout))
+		})
+
+		It("should prefer tool-specific values over global ones", func() {
+			config := &core.Config{}
+			Expect(config.SetTimeouts("20m,code=30m", "code=5m", "1m")).To(Succeed())
+
+			Expect(config.GetTimeouts("chat").Total).To(Equal(20 * time.Minute))
+			Expect(config.GetTimeouts("code").Total).To(Equal(30 * time.Minute))
+			Expect(config.GetTimeouts("code").FirstToken).To(Equal(5 * time.Minute))
+			Expect(config.GetTimeouts("chat").FirstToken).To(Equal(core.DefaultFirstTokenTimeout))
+			Expect(config.GetTimeouts("chat").Idle).To(Equal(time.Minute))
+		})
+
+		DescribeTable("invalid specs",
+			func(spec string) {
+				Expect((&core.Config{}).SetTimeouts(spec, "", "")).NotTo(Succeed())
+			},
+			Entry("not a duration", "soon"),
+			Entry("negative", "chat=-1s"),
+			Entry("missing duration", "chat="),
+		)
+	})
+
+	Describe("Chat deadlines", func() {
+		var (
+			ollama  *fakeOllama
+			config  *core.Config
+			factory *core.HandlerFactory
+		)
+
+		BeforeEach(func() {
+			ollama = newFakeOllama()
+			ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+				return []api.ChatResponse{
+					{Message: api.Message{Role: "assistant", Content: "a"}},
+					{Message: api.Message{Role: "assistant", Content: "b"}},
+					{Message: api.Message{Role: "assistant"}, Done: true},
+				}
+			}
+			config = &core.Config{
+				Client:      ollama.Client(),
+				ContextSize: 32000,
+				ChatModel:   "test-chat-model",
+				KeepAlive:   "1m",
+				Timeouts: map[string]core.Timeouts{
+					"chat": {FirstToken: 200 * time.Millisecond, Idle: 50 * time.Millisecond},
+				},
+			}
+			factory = core.NewHandlerFactory(core.NewServer(config))
+		})
+
+		AfterEach(func() {
+			ollama.Close()
+		})
+
+		It("should tolerate a slow first token within the limit", func() {
+			ollama.firstDelay = 100 * time.Millisecond
+
+			_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hi"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Response).To(Equal("ab"))
+		})
+
+		It("should fail when the first token takes too long", func() {
+			ollama.firstDelay = time.Second
+
+			_, _, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hi"})
+			Expect(err).To(MatchError(ContainSubstring("first-token timeout")))
+		})
+
+		It("should stop when the generation stalls", func() {
+			ollama.chunkDelay = 500 * time.Millisecond
+
+			_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hi"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Response).To(Equal("a"))
+			Expect(output.TruncatedReason).To(Equal("timeout"))
+		})
+	})
+})
+
+var _ = Describe("Partial output", func() {
+	var (
+		ollama  *fakeOllama
+		config  *core.Config
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{
+				{Message: api.Message{Role: "assistant", Content: "partial"}},
+				{Message: api.Message{Role: "assistant", Content: " answer"}},
+				{Message: api.Message{Role: "assistant"}, Done: true},
+			}
+		}
+		ollama.chunkDelay = 300 * time.Millisecond
+		config = &core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32000,
+			ChatModel:   "test-chat-model",
+			CodeModel:   "test-code-model",
+			KeepAlive:   "1m",
+		}
+		factory = core.NewHandlerFactory(core.NewServer(config))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should return the text generated before the deadline", func() {
+		config.Timeouts = map[string]core.Timeouts{"chat": {Total: 150 * time.Millisecond}}
+
+		_, output, err := factory.ChatHandler()(context.Background(), nil, core.ChatInput{Message: "hi"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Response).To(Equal("partial"))
+		Expect(output.Truncated).To(BeTrue())
+		Expect(output.TruncatedReason).To(Equal("timeout"))
+	})
+
+	It("should return the text generated before a cancellation", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+		defer cancel()
+
+		_, output, err := factory.ChatHandler()(ctx, nil, core.ChatInput{Message: "hi"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.TruncatedReason).To(Equal("cancelled"))
+		Eventually(ollama.aborted.Load).Should(BeEquivalentTo(1))
+	})
+
+	It("should flag answers cut by the length limit", func() {
+		ollama.chunkDelay = 0
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{{Message: api.Message{Role: "assistant", Content: "cut"}, Done: true, DoneReason: "length"}}
+		}
+
+		_, output, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{Message: "hi"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Truncated).To(BeTrue())
+		Expect(output.TruncatedReason).To(Equal("length"))
+	})
+
+	It("should abort the Ollama request when the MCP client cancels", func() {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "test"}, nil)
+		mcp.AddTool(server, &mcp.Tool{Name: "chat"}, factory.ChatHandler())
+		session := connectClient(server, nil)
+		defer func() { _ = session.Close() }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+		defer cancel()
+		_, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "chat", Arguments: map[string]any{"model": "test-chat-model", "message": "hi"}})
+		Expect(err).To(HaveOccurred())
+
+		Eventually(ollama.aborted.Load, "200ms").Should(BeEquivalentTo(1))
+	})
+})
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"github.com/ollama/ollama/api"
+)
+
+// Usage reports the token counts and timings of a generation
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens" jsonschema:"number of tokens in the prompt"`
+	CompletionTokens int     `json:"completion_tokens" jsonschema:"number of tokens generated"`
+	TotalTokens      int     `json:"total_tokens" jsonschema:"prompt and generated tokens combined"`
+	TokensPerSecond  float64 `json:"tokens_per_second" jsonschema:"generation speed in tokens per second"`
+	LoadMs           int64   `json:"load_ms" jsonschema:"time spent loading the model in milliseconds"`
+	PromptEvalMs     int64   `json:"prompt_eval_ms" jsonschema:"time spent processing the prompt in milliseconds"`
+	EvalMs           int64   `json:"eval_ms" jsonschema:"time spent generating in milliseconds"`
+	TotalMs          int64   `json:"total_ms" jsonschema:"total time of the request in milliseconds"`
+	DoneReason       string  `json:"done_reason,omitempty" jsonschema:"why generation stopped: stop when complete, length when truncated by num_predict or the context size"`
+}
+
+// newUsage builds the usage report of a final chat or generate response
+func newUsage(metrics api.Metrics, doneReason string) *Usage {
+	usage := &Usage{
+		PromptTokens:     metrics.PromptEvalCount,
+		CompletionTokens: metrics.EvalCount,
+		TotalTokens:      metrics.PromptEvalCount + metrics.EvalCount,
+		LoadMs:           metrics.LoadDuration.Milliseconds(),
+		PromptEvalMs:     metrics.PromptEvalDuration.Milliseconds(),
+		EvalMs:           metrics.EvalDuration.Milliseconds(),
+		TotalMs:          metrics.TotalDuration.Milliseconds(),
+		DoneReason:       doneReason,
+	}
+	if metrics.EvalDuration > 0 {
+		usage.TokensPerSecond = float64(metrics.EvalCount) / metrics.EvalDuration.Seconds()
+	}
+	return usage
+}
+
+// addMetrics accumulates the metrics of several requests that produced one answer
+func addMetrics(total *api.Metrics, metrics api.Metrics) {
+	total.TotalDuration += metrics.TotalDuration
+	total.LoadDuration += metrics.LoadDuration
+	total.PromptEvalCount += metrics.PromptEvalCount
+	total.PromptEvalDuration += metrics.PromptEvalDuration
+	total.EvalCount += metrics.EvalCount
+	total.EvalDuration += metrics.EvalDuration
+}
+
+// add accumulates the usage of another generation, for reports covering several answers
+func (u *Usage) add(other *Usage) {
+	if other == nil {
+		return
+	}
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.LoadMs += other.LoadMs
+	u.PromptEvalMs += other.PromptEvalMs
+	u.EvalMs += other.EvalMs
+	u.TotalMs += other.TotalMs
+	if u.EvalMs > 0 {
+		u.TokensPerSecond = float64(u.CompletionTokens) / (float64(u.EvalMs) / 1000)
+	}
+}
@@ -0,0 +1,67 @@
+package core_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ollama/ollama/api"
+
+	"github.com/efortin/ollama-mcp/internal/core"
+)
+
+var _ = Describe("Usage", func() {
+	var (
+		ollama  *fakeOllama
+		factory *core.HandlerFactory
+	)
+
+	BeforeEach(func() {
+		ollama = newFakeOllama()
+		ollama.chat = func(req api.ChatRequest) []api.ChatResponse {
+			return []api.ChatResponse{
+				{Message: api.Message{Role: "assistant", Content: "partial"}},
+				{
+					Message:    api.Message{Role: "assistant"},
+					Done:       true,
+					DoneReason: "length",
+					Metrics: api.Metrics{
+						PromptEvalCount: 12,
+						EvalCount:       50,
+						EvalDuration:    2 * time.Second,
+						LoadDuration:    1500 * time.Millisecond,
+						TotalDuration:   4 * time.Second,
+					},
+				},
+			}
+		}
+		factory = core.NewHandlerFactory(core.NewServer(&core.Config{
+			Client:      ollama.Client(),
+			ContextSize: 32000,
+			ChatModel:   "test-chat-model",
+			CodeModel:   "test-code-model",
+			KeepAlive:   "1m",
+		}))
+	})
+
+	AfterEach(func() {
+		ollama.Close()
+	})
+
+	It("should report token counts, speed and done reason", func() {
+		_, output, err := factory.CodeHandler()(context.Background(), nil, core.CodeInput{Message: "hi"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Usage).To(Equal(&core.Usage{
+			PromptTokens:     12,
+			CompletionTokens: 50,
+			TotalTokens:      62,
+			TokensPerSecond:  25,
+			LoadMs:           1500,
+			EvalMs:           2000,
+			TotalMs:          4000,
+			DoneReason:       "length",
+		}))
+	})
+})